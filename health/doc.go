@@ -0,0 +1,23 @@
+// Package health provides liveness and readiness checks of the service
+// components, exposed as JSON reports for the orchestrator probes and load
+// balancers.
+//
+// Critical checks fail the readiness, while the failure of non-critical ones
+// only degrades it. Once the server begins shutdown, the readiness fails, so
+// that traffic is drained before the listener is closed.
+//
+// Basic Usage
+//
+//	checker := health.NewChecker()
+//	checker.Register("database", db.PingContext, health.WithTimeout(time.Second))
+//	checker.Register("cache", cache.Ping, health.NonCritical())
+//
+//	mux.AddRoute("GET", "/livez", checker.LivenessHandler())
+//	mux.AddRoute("GET", "/readyz", checker.ReadinessHandler())
+//
+//	srv := http.New(&stdhttp.Server{Addr: ":8080", Handler: mux})
+//	srv.WithShutdownHook(checker.Shutdown)
+//	srv.WithDrainDelay(5 * time.Second)
+//
+//	err := srv.Serve(ctx)
+package health
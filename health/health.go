@@ -0,0 +1,261 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+)
+
+const defTimeout = 5 * time.Second
+
+// Status is the health status of a check or the whole service.
+type Status string
+
+// Available health statuses.
+const (
+	// StatusOK reports that all checks pass.
+	StatusOK Status = "ok"
+	// StatusDegraded reports that only non-critical checks fail, and the
+	// service is still able to serve traffic.
+	StatusDegraded Status = "degraded"
+	// StatusFailing reports that a critical check fails.
+	StatusFailing Status = "failing"
+	// StatusShuttingDown reports that the service is shutting down and
+	// should not receive new traffic.
+	StatusShuttingDown Status = "shutting_down"
+)
+
+// CheckFunc reports the health of a component, such as the database
+// connection. It should return once the context is done.
+type CheckFunc func(ctx context.Context) error
+
+// CheckOption configures the registered check.
+type CheckOption func(*check)
+
+// WithTimeout limits the duration of the check, 5s by default.
+func WithTimeout(d time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// NonCritical marks the check whose failure degrades the service, but does
+// not fail the readiness.
+func NonCritical() CheckOption {
+	return func(c *check) {
+		c.critical = false
+	}
+}
+
+// Liveness includes the check in the liveness report. It should only be used
+// for failures that a restart recovers from, such as a deadlock.
+func Liveness() CheckOption {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	timeout  time.Duration
+	critical bool
+	liveness bool
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status   Status `json:"status"`
+	Critical bool   `json:"critical"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report is the health of the service with the results of its checks.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Healthy reports whether the service is able to serve traffic.
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK || r.Status == StatusDegraded
+}
+
+// Checker runs the registered checks for the liveness and readiness reports.
+// It is safe for concurrent use.
+type Checker struct {
+	checks   []check
+	shutdown int32
+	mu       sync.RWMutex
+}
+
+// NewChecker returns a new instance of Checker.
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Register adds the named check, which is critical by default. It panics if
+// the name is already registered.
+func (c *Checker) Register(name string, fn CheckFunc, opts ...CheckOption) {
+	ch := check{
+		name:     name,
+		fn:       fn,
+		timeout:  defTimeout,
+		critical: true,
+	}
+
+	for _, opt := range opts {
+		opt(&ch)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.checks {
+		if existing.name == name {
+			panic(fmt.Sprintf("health: check %q already registered", name))
+		}
+	}
+
+	c.checks = append(c.checks, ch)
+}
+
+// Shutdown marks the service as shutting down, so that the readiness fails
+// while the requests in flight are completed. It is meant to be registered
+// as the server shutdown hook.
+func (c *Checker) Shutdown() {
+	atomic.StoreInt32(&c.shutdown, 1)
+}
+
+// Liveness runs the liveness checks and returns the report.
+func (c *Checker) Liveness(ctx context.Context) Report {
+	return c.run(ctx, true)
+}
+
+// Readiness runs all checks and returns the report. It reports shutting down
+// without running the checks once Shutdown is called.
+func (c *Checker) Readiness(ctx context.Context) Report {
+	if atomic.LoadInt32(&c.shutdown) == 1 {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	return c.run(ctx, false)
+}
+
+// LivenessHandler returns the /livez handler responding with the liveness
+// report, and 503 Service Unavailable if the service is not healthy.
+func (c *Checker) LivenessHandler() http.HandlerFunc {
+	return handler(c.Liveness)
+}
+
+// ReadinessHandler returns the /readyz handler responding with the readiness
+// report, and 503 Service Unavailable if the service is not healthy.
+func (c *Checker) ReadinessHandler() http.HandlerFunc {
+	return handler(c.Readiness)
+}
+
+func handler(report func(context.Context) Report) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rep := report(r.Context())
+
+		status := http.StatusOK
+		if !rep.Healthy() {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		jsonapi.WriteStatus(w, status, rep)
+	}
+}
+
+// run runs the checks concurrently, each limited by its timeout.
+func (c *Checker) run(ctx context.Context, liveness bool) Report {
+	c.mu.RLock()
+	checks := make([]check, 0, len(c.checks))
+
+	for _, ch := range c.checks {
+		if !liveness || ch.liveness {
+			checks = append(checks, ch)
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+
+	for i, ch := range checks {
+		wg.Add(1)
+
+		go func(i int, ch check) {
+			defer wg.Done()
+			results[i] = ch.run(ctx)
+		}(i, ch)
+	}
+
+	wg.Wait()
+
+	rep := Report{Status: StatusOK}
+
+	if len(checks) > 0 {
+		rep.Checks = make(map[string]Result, len(checks))
+	}
+
+	for i, res := range results {
+		rep.Checks[checks[i].name] = res
+
+		switch {
+		case res.Status == StatusOK:
+		case res.Critical:
+			rep.Status = StatusFailing
+		case rep.Status == StatusOK:
+			rep.Status = StatusDegraded
+		}
+	}
+
+	return rep
+}
+
+// run runs the check, which fails when it does not return within the
+// timeout.
+func (ch check) run(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, ch.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- ch.fn(ctx)
+	}()
+
+	var err error
+
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{
+		Status:   StatusOK,
+		Critical: ch.critical,
+		Duration: time.Since(start).String(),
+	}
+
+	if err != nil {
+		res.Status = StatusFailing
+		res.Error = err.Error()
+	}
+
+	return res
+}
@@ -0,0 +1,136 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pass(context.Context) error {
+	return nil
+}
+
+func fail(context.Context) error {
+	return errors.New("connection refused")
+}
+
+func hang(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestChecker_Readiness(t *testing.T) {
+	tests := map[string]struct {
+		giveChecks map[string]CheckFunc
+		giveOpts   []CheckOption
+		wantStatus Status
+		wantErrors map[string]string
+	}{
+		"no checks": {
+			wantStatus: StatusOK,
+		},
+		"all pass": {
+			giveChecks: map[string]CheckFunc{"db": pass, "cache": pass},
+			wantStatus: StatusOK,
+		},
+		"critical failure": {
+			giveChecks: map[string]CheckFunc{"db": fail, "cache": pass},
+			wantStatus: StatusFailing,
+			wantErrors: map[string]string{"db": "connection refused"},
+		},
+		"non-critical failure": {
+			giveChecks: map[string]CheckFunc{"db": fail},
+			giveOpts:   []CheckOption{NonCritical()},
+			wantStatus: StatusDegraded,
+			wantErrors: map[string]string{"db": "connection refused"},
+		},
+		"timeout": {
+			giveChecks: map[string]CheckFunc{"db": hang},
+			giveOpts:   []CheckOption{WithTimeout(10 * time.Millisecond)},
+			wantStatus: StatusFailing,
+			wantErrors: map[string]string{"db": "context deadline exceeded"},
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewChecker()
+			for name, fn := range tc.giveChecks {
+				c.Register(name, fn, tc.giveOpts...)
+			}
+
+			rep := c.Readiness(context.Background())
+
+			assert.Equal(t, tc.wantStatus, rep.Status)
+			assert.Len(t, rep.Checks, len(tc.giveChecks))
+
+			for name, res := range rep.Checks {
+				assert.Equal(t, tc.wantErrors[name], res.Error)
+				assert.NotEmpty(t, res.Duration)
+			}
+		})
+	}
+}
+
+func TestChecker_Liveness(t *testing.T) {
+	c := NewChecker()
+	c.Register("db", fail)
+	c.Register("deadlock", pass, Liveness())
+
+	rep := c.Liveness(context.Background())
+
+	assert.Equal(t, StatusOK, rep.Status)
+	assert.Equal(t, map[string]Result{"deadlock": {Status: StatusOK, Critical: true, Duration: rep.Checks["deadlock"].Duration}}, rep.Checks)
+
+	c.Shutdown()
+
+	assert.Equal(t, StatusOK, c.Liveness(context.Background()).Status, "service is alive while shutting down")
+	assert.Equal(t, Report{Status: StatusShuttingDown}, c.Readiness(context.Background()))
+}
+
+func TestChecker_Register(t *testing.T) {
+	c := NewChecker()
+	c.Register("db", pass)
+
+	assert.PanicsWithValue(t, `health: check "db" already registered`, func() {
+		c.Register("db", pass)
+	})
+}
+
+func TestChecker_Handlers(t *testing.T) {
+	c := NewChecker()
+	c.Register("db", pass)
+	c.Register("cache", fail, NonCritical())
+
+	rec := httptest.NewRecorder()
+	c.ReadinessHandler()(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Body.String(), `"status":"degraded"`)
+	assert.Contains(t, rec.Body.String(), `"cache":{"status":"failing","critical":false`)
+	assert.Contains(t, rec.Body.String(), `"error":"connection refused"`)
+
+	c.Shutdown()
+
+	rec = httptest.NewRecorder()
+	c.ReadinessHandler()(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"shutting_down"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	c.LivenessHandler()(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
@@ -0,0 +1,26 @@
+/*
+Package hedge provides a RoundTripper for the HTTP transport that sends hedged
+requests, a sibling of the retry package for latency-sensitive calls.
+
+A copy of the idempotent request is sent when the previous one has not
+responded within the delay. The first successful response wins, and the
+requests still in flight are canceled. The delay is either fixed, or derived
+from the percentile of the observed latencies, so that only the slowest
+requests are hedged.
+
+Basic Usage
+
+	client := hedge.WithPolicy(http.DefaultClient, hedge.Policy{
+		MaxAttempts: 3,
+		Delay:       50 * time.Millisecond,
+	})
+
+	res, err := client.Get(profilesURL)
+
+Hedging does not retry failed requests, it composes with the retry transport
+applied on top of it.
+
+	client := hedge.WithPolicy(http.DefaultClient, hedge.DefaultPolicy())
+	client = retry.WithPolicy(client, retry.DefaultPolicy())
+*/
+package hedge
@@ -0,0 +1,114 @@
+package hedge
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defMaxAttempts = 2
+	// window is the number of the latest latency samples the percentile is
+	// computed from.
+	window = 256
+	// minSamples is the number of samples required before the percentile
+	// delay replaces the fixed one.
+	minSamples = 20
+)
+
+// Policy defines when and how many copies of the request are sent.
+type Policy struct {
+	// MaxAttempts is the total number of requests sent, including the first
+	// one. Zero value sends a single hedged request.
+	MaxAttempts int
+	// Delay is the wait before sending each subsequent request. Zero value
+	// sends all of them at once. With the Percentile set, it is used until
+	// enough latencies are observed.
+	Delay time.Duration
+	// Percentile derives the delay from the observed latencies of successful
+	// requests, such as 0.95 hedges requests slower than 95% of them. Zero
+	// value uses the fixed Delay.
+	Percentile float64
+	// Methods is a list of hedged request methods, which must be idempotent.
+	// Zero value hedges GET, HEAD and OPTIONS requests.
+	Methods []string
+}
+
+// DefaultPolicy is default hedging policy configuration, which sends the
+// second request once the first one is slower than 95% of the previous ones.
+func DefaultPolicy() Policy {
+	const (
+		defDelay      = 100 * time.Millisecond
+		defPercentile = 0.95
+	)
+
+	return Policy{
+		MaxAttempts: defMaxAttempts,
+		Delay:       defDelay,
+		Percentile:  defPercentile,
+	}
+}
+
+func (p Policy) methods() map[string]bool {
+	methods := p.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	}
+
+	m := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		m[method] = true
+	}
+
+	return m
+}
+
+// latencies is a ring buffer of the latest latency samples.
+type latencies struct {
+	samples [window]time.Duration
+	count   int
+	mu      sync.Mutex
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.samples[l.count%window] = d
+	l.count++
+}
+
+// percentile returns the p-th percentile of the samples, and false if there
+// are not enough of them.
+func (l *latencies) percentile(p float64) (time.Duration, bool) {
+	l.mu.Lock()
+
+	n := l.count
+	if n > window {
+		n = window
+	}
+
+	if n < minSamples {
+		l.mu.Unlock()
+		return 0, false
+	}
+
+	sorted := make([]time.Duration, n)
+	copy(sorted, l.samples[:n])
+
+	l.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	i := int(p*float64(n)+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	if i >= n {
+		i = n - 1
+	}
+
+	return sorted[i], true
+}
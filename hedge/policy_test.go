@@ -0,0 +1,39 @@
+package hedge
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	p := DefaultPolicy()
+
+	assert.Equal(t, 2, p.MaxAttempts)
+	assert.Equal(t, 100*time.Millisecond, p.Delay)
+	assert.Equal(t, 0.95, p.Percentile)
+}
+
+func TestTransport_delay(t *testing.T) {
+	tr := WithPolicy(&http.Client{}, Policy{Delay: time.Second, Percentile: 0.9}).Transport.(transport)
+
+	for i := 1; i < minSamples; i++ {
+		tr.latencies.add(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.Equal(t, time.Second, tr.delay(), "fixed delay is used until enough samples")
+
+	for i := minSamples; i <= 100; i++ {
+		tr.latencies.add(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.Equal(t, 90*time.Millisecond, tr.delay())
+
+	for i := 0; i < window; i++ {
+		tr.latencies.add(5 * time.Millisecond)
+	}
+
+	assert.Equal(t, 5*time.Millisecond, tr.delay(), "only the latest samples are used")
+}
@@ -0,0 +1,214 @@
+package hedge
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// NewHTTPClient returns a new http.Client with the http.DefaultTransport
+// wrapped with hedging round tripper.
+func NewHTTPClient(policy Policy) *http.Client {
+	return WithPolicy(http.DefaultClient, policy)
+}
+
+// WithPolicy returns a copy of http.Client with hedging transport. It panics
+// if the policy percentile is out of range.
+func WithPolicy(client *http.Client, policy Policy) *http.Client {
+	if policy.Percentile < 0 || policy.Percentile > 1 {
+		panic("hedge: percentile must be between 0 and 1")
+	}
+
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defMaxAttempts
+	}
+
+	cp := *client
+	if cp.Transport == nil {
+		cp.Transport = http.DefaultTransport
+	}
+
+	cp.Transport = transport{
+		RoundTripper: cp.Transport,
+		Policy:       policy,
+		methods:      policy.methods(),
+		latencies:    &latencies{},
+	}
+
+	return &cp
+}
+
+// transport is a wrapper for HTTP RoundTripper that is responsible for
+// hedging.
+type transport struct {
+	http.RoundTripper
+
+	Policy    Policy
+	methods   map[string]bool
+	latencies *latencies
+}
+
+type attempt struct {
+	resp    *http.Response
+	err     error
+	index   int
+	latency time.Duration
+}
+
+// RoundTrip sends the request, and its copies after the delay while none of
+// the previous ones has responded. The first successful response is returned
+// and the other requests are canceled. Failed requests are not retried, and
+// the last failure is returned if none succeeds.
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.hedged(req) {
+		return t.RoundTripper.RoundTrip(req)
+	}
+
+	results := make(chan attempt, t.Policy.MaxAttempts)
+	cancels := make([]context.CancelFunc, 0, t.Policy.MaxAttempts)
+	inflight := 0
+
+	send := func() error {
+		ctx, cancel := context.WithCancel(req.Context())
+		r := req.Clone(ctx)
+
+		if inflight > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+
+			r.Body = body
+		}
+
+		inflight++
+		cancels = append(cancels, cancel)
+
+		go func(i int) {
+			start := time.Now()
+			resp, err := t.RoundTripper.RoundTrip(r) // nolint:bodyclose // closed by the client or discarded
+			results <- attempt{resp: resp, err: err, index: i, latency: time.Since(start)}
+		}(len(cancels) - 1)
+
+		return nil
+	}
+
+	send() // nolint:errcheck // the first request is sent with the original body
+
+	sent := 1
+	delay := t.delay()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var last *attempt
+
+	for inflight > 0 {
+		select {
+		case res := <-results:
+			inflight--
+
+			if last != nil {
+				last.discard(cancels[last.index])
+			}
+
+			last = &res
+
+			if res.err == nil && res.resp.StatusCode < http.StatusInternalServerError {
+				t.latencies.add(res.latency)
+
+				for i, cancel := range cancels {
+					if i != res.index {
+						cancel()
+					}
+				}
+
+				go drain(results, inflight, cancels)
+
+				return res.response(cancels[res.index]), nil
+			}
+		case <-timer.C:
+			if sent < t.Policy.MaxAttempts {
+				if err := send(); err != nil {
+					break
+				}
+
+				sent++
+
+				timer.Reset(delay)
+			}
+		}
+	}
+
+	if last.err != nil {
+		cancels[last.index]()
+		return nil, last.err
+	}
+
+	return last.response(cancels[last.index]), nil
+}
+
+// CloseIdleConnections closes all idle connections if internal transport
+// supports it.
+func (t transport) CloseIdleConnections() {
+	if tr, ok := t.RoundTripper.(interface{ CloseIdleConnections() }); ok {
+		tr.CloseIdleConnections()
+	}
+}
+
+func (t transport) hedged(req *http.Request) bool {
+	if !t.methods[req.Method] {
+		return false
+	}
+
+	// The request body can be sent again only when it can be rewound.
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func (t transport) delay() time.Duration {
+	if t.Policy.Percentile > 0 {
+		if d, ok := t.latencies.percentile(t.Policy.Percentile); ok {
+			return d
+		}
+	}
+
+	return t.Policy.Delay
+}
+
+// response returns the response of the attempt, which cancels its request
+// context when the body is closed.
+func (a attempt) response(cancel context.CancelFunc) *http.Response {
+	a.resp.Body = cancelBody{ReadCloser: a.resp.Body, cancel: cancel}
+	return a.resp
+}
+
+// discard cancels the attempt and releases its connection.
+func (a attempt) discard(cancel context.CancelFunc) {
+	if a.resp != nil {
+		a.resp.Body.Close()
+	}
+
+	cancel()
+}
+
+// drain discards the canceled attempts still in flight once they complete.
+func drain(results <-chan attempt, n int, cancels []context.CancelFunc) {
+	for ; n > 0; n-- {
+		res := <-results
+		res.discard(cancels[res.index])
+	}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
@@ -0,0 +1,177 @@
+package hedge
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPolicy(t *testing.T) {
+	client := http.Client{}
+	hedgeClient := WithPolicy(&client, Policy{})
+
+	require.NotNil(t, hedgeClient)
+	require.IsType(t, transport{}, hedgeClient.Transport)
+
+	tr := hedgeClient.Transport.(transport)
+
+	assert.Equal(t, 2, tr.Policy.MaxAttempts)
+	assert.Equal(t, map[string]bool{"GET": true, "HEAD": true, "OPTIONS": true}, tr.methods)
+
+	assert.PanicsWithValue(t, "hedge: percentile must be between 0 and 1", func() {
+		WithPolicy(&client, Policy{Percentile: 95})
+	})
+}
+
+type testResponse struct {
+	// block responds only when the request is canceled.
+	block  bool
+	delay  time.Duration
+	status int
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	tests := map[string]struct {
+		givePolicy    Policy
+		giveMethod    string
+		giveBody      string
+		giveResponses []testResponse
+		wantBody      string
+		wantStatus    int
+		wantRequests  int
+		wantCanceled  int
+	}{
+		"fast first request": {
+			givePolicy:    Policy{Delay: time.Second},
+			giveMethod:    http.MethodGet,
+			giveResponses: []testResponse{{}},
+			wantBody:      "response 1",
+			wantStatus:    http.StatusOK,
+			wantRequests:  1,
+		},
+		"slow first request": {
+			givePolicy:    Policy{Delay: 10 * time.Millisecond},
+			giveMethod:    http.MethodGet,
+			giveResponses: []testResponse{{block: true}, {}},
+			wantBody:      "response 2",
+			wantStatus:    http.StatusOK,
+			wantRequests:  2,
+			wantCanceled:  1,
+		},
+		"up to max attempts": {
+			givePolicy:    Policy{MaxAttempts: 3, Delay: 10 * time.Millisecond},
+			giveMethod:    http.MethodGet,
+			giveResponses: []testResponse{{block: true}, {block: true}, {}},
+			wantBody:      "response 3",
+			wantStatus:    http.StatusOK,
+			wantRequests:  3,
+			wantCanceled:  2,
+		},
+		"not idempotent method": {
+			givePolicy:    Policy{},
+			giveMethod:    http.MethodPost,
+			giveBody:      "payload",
+			giveResponses: []testResponse{{delay: 20 * time.Millisecond}},
+			wantBody:      "response 1 payload",
+			wantStatus:    http.StatusOK,
+			wantRequests:  1,
+		},
+		"rewound body": {
+			givePolicy:    Policy{Methods: []string{http.MethodPut}},
+			giveMethod:    http.MethodPut,
+			giveBody:      "payload",
+			giveResponses: []testResponse{{block: true}, {}},
+			wantBody:      "response 2 payload",
+			wantStatus:    http.StatusOK,
+			wantRequests:  2,
+			wantCanceled:  1,
+		},
+		"failure is skipped": {
+			givePolicy: Policy{Delay: 10 * time.Millisecond},
+			giveMethod: http.MethodGet,
+			giveResponses: []testResponse{
+				{delay: 100 * time.Millisecond},
+				{status: http.StatusServiceUnavailable},
+			},
+			wantBody:     "response 1",
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		"all failed": {
+			givePolicy: Policy{},
+			giveMethod: http.MethodGet,
+			giveResponses: []testResponse{
+				{delay: 20 * time.Millisecond, status: http.StatusBadGateway},
+				{status: http.StatusBadGateway},
+			},
+			wantBody:     "response 1",
+			wantStatus:   http.StatusBadGateway,
+			wantRequests: 2,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				requests int32
+				canceled int32
+			)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(atomic.AddInt32(&requests, 1))
+				body, _ := ioutil.ReadAll(r.Body)
+				give := tc.giveResponses[n-1]
+
+				if give.block {
+					<-r.Context().Done()
+					atomic.AddInt32(&canceled, 1)
+
+					return
+				}
+
+				time.Sleep(give.delay)
+
+				if give.status != 0 {
+					w.WriteHeader(give.status)
+				}
+
+				io.WriteString(w, strings.TrimSpace(fmt.Sprintf("response %d %s", n, body)))
+			}))
+			t.Cleanup(srv.Close)
+
+			client := WithPolicy(srv.Client(), tc.givePolicy)
+
+			req, err := http.NewRequest(tc.giveMethod, srv.URL, strings.NewReader(tc.giveBody))
+			require.NoError(t, err)
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			assert.Equal(t, tc.wantBody, string(body))
+			assert.Equal(t, tc.wantRequests, int(atomic.LoadInt32(&requests)))
+
+			assert.Eventually(t, func() bool {
+				return int(atomic.LoadInt32(&canceled)) == tc.wantCanceled
+			}, time.Second, 10*time.Millisecond, "slow requests are canceled")
+		})
+	}
+}
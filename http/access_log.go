@@ -0,0 +1,128 @@
+package http
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+// AccessLogFormat is the format of access log lines.
+type AccessLogFormat int
+
+// Available access log formats.
+const (
+	// AccessLogKeyValue logs key-value pairs, along with the logger context
+	// fields such as request identifier.
+	AccessLogKeyValue AccessLogFormat = iota
+	// AccessLogCommon logs in the Common Log Format.
+	AccessLogCommon
+	// AccessLogCombined logs in the Combined Log Format.
+	AccessLogCombined
+)
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogConfig is a configuration for the access log middleware.
+type AccessLogConfig struct {
+	// Format is the log line format.
+	Format AccessLogFormat
+	// SampleRate is a fraction of requests to be logged, from 0 to 1. Zero
+	// value logs all requests. Server errors are always logged.
+	SampleRate float64
+	// SkipPaths is a list of request paths never logged, such as health
+	// check endpoints.
+	SkipPaths []string
+	// Skip is a custom func for the requests that should not be logged.
+	Skip func(*http.Request) bool
+}
+
+// AccessLog returns a middleware func that logs served requests with the
+// Info level.
+func AccessLog(log logger.Logger, cfg AccessLogConfig) MiddlewareFunc {
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skip[p] = true
+	}
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] || (cfg.Skip != nil && cfg.Skip(r)) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			ww, rec := wrapWriter(w)
+
+			h.ServeHTTP(ww, r)
+
+			if rec.Status() < http.StatusInternalServerError &&
+				cfg.SampleRate > 0 && rand.Float64() >= cfg.SampleRate { // nolint:gosec
+				return
+			}
+
+			entry := accessEntry{
+				r:        r,
+				start:    start,
+				duration: time.Since(start),
+				status:   rec.Status(),
+				bytes:    rec.bytes,
+			}
+
+			switch cfg.Format {
+			case AccessLogCommon:
+				log.Infof("%s", entry.common())
+			case AccessLogCombined:
+				log.Infof("%s %q %q", entry.common(), r.Referer(), r.UserAgent())
+			default:
+				entry.keyValue(log.WithContext(r.Context())).Infof("http request")
+			}
+		}
+	}
+}
+
+type accessEntry struct {
+	r        *http.Request
+	start    time.Time
+	duration time.Duration
+	status   int
+	bytes    int64
+}
+
+func (e accessEntry) common() string {
+	host, _, err := net.SplitHostPort(e.r.RemoteAddr)
+	if err != nil {
+		host = e.r.RemoteAddr
+	}
+
+	user := "-"
+	if u, _, ok := e.r.BasicAuth(); ok && u != "" {
+		user = u
+	}
+
+	size := "-"
+	if e.bytes > 0 {
+		size = strconv.FormatInt(e.bytes, 10)
+	}
+
+	return fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`,
+		host, user, e.start.Format(clfTimeFormat),
+		e.r.Method, e.r.URL.RequestURI(), e.r.Proto, e.status, size)
+}
+
+func (e accessEntry) keyValue(log logger.Logger) logger.Logger {
+	return log.
+		With("method", e.r.Method).
+		With("route", RoutePattern(e.r.Context())).
+		With("path", e.r.URL.Path).
+		With("status", e.status).
+		With("bytes", e.bytes).
+		With("duration", e.duration).
+		With("remote", e.r.RemoteAddr).
+		With("user_agent", e.r.UserAgent())
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+func TestAccessLog(t *testing.T) {
+	tests := map[string]struct {
+		giveConfig AccessLogConfig
+		givePath   string
+		giveStatus int
+		wantLine   string
+	}{
+		"key value": {
+			AccessLogConfig{},
+			"/users/1",
+			http.StatusCreated,
+			`^INF: http request request_id=abc method=GET route=/users/:id path=/users/1 status=201 bytes=5 ` +
+				`duration=\S+ remote=192.0.2.1:1234 user_agent=test-agent\n$`,
+		},
+		"common": {
+			AccessLogConfig{Format: AccessLogCommon},
+			"/users/1?q=1",
+			http.StatusOK,
+			`^INF: 192\.0\.2\.1 - - \[.+\] "GET /users/1\?q=1 HTTP/1\.1" 200 5\n$`,
+		},
+		"combined": {
+			AccessLogConfig{Format: AccessLogCombined},
+			"/users/1",
+			http.StatusOK,
+			`^INF: 192\.0\.2\.1 - - \[.+\] "GET /users/1 HTTP/1\.1" 200 5 "http://ref" "test-agent"\n$`,
+		},
+		"skip path": {
+			AccessLogConfig{SkipPaths: []string{"/healthz"}},
+			"/healthz",
+			http.StatusOK,
+			`^$`,
+		},
+		"skip func": {
+			AccessLogConfig{Skip: func(r *http.Request) bool { return r.URL.Query().Get("skip") != "" }},
+			"/users/1?skip=1",
+			http.StatusOK,
+			`^$`,
+		},
+		"sampled out": {
+			AccessLogConfig{SampleRate: 1e-9},
+			"/users/1",
+			http.StatusOK,
+			`^$`,
+		},
+		"sampled out but server error": {
+			AccessLogConfig{SampleRate: 1e-9, Format: AccessLogCommon},
+			"/users/1",
+			http.StatusBadGateway,
+			`^INF: .+ 502 5\n$`,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			m := Mux{}
+			m.WithMiddleware(AccessLog(logger.New(&buf, logger.Info), tc.giveConfig), RequestID)
+
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.giveStatus)
+				io.WriteString(w, "hello")
+			}
+
+			m.AddRoute("GET", "/users/:id", handler)
+			m.AddRoute("GET", "/healthz", handler)
+
+			req := httptest.NewRequest("GET", tc.givePath, nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("User-Agent", "test-agent")
+			req.Header.Set("Referer", "http://ref")
+			req.Header.Set(RequestIDHeader, "abc")
+
+			m.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Regexp(t, regexp.MustCompile(tc.wantLine), buf.String())
+		})
+	}
+}
+
+func TestWrapWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w, rw := wrapWriter(rec)
+
+	_, ok := w.(http.Flusher)
+	assert.True(t, ok)
+
+	_, ok = w.(http.Hijacker)
+	assert.False(t, ok)
+
+	w.(http.Flusher).Flush()
+
+	assert.Equal(t, http.StatusOK, rw.Status())
+	assert.True(t, rec.Flushed)
+
+	w, _ = wrapWriter(struct{ http.ResponseWriter }{rec})
+
+	_, ok = w.(http.Flusher)
+	assert.False(t, ok)
+}
@@ -0,0 +1,399 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+)
+
+const defaultCompressMinSize = 1024
+
+// defaultCompressTypes are the media types compressed by default.
+var defaultCompressTypes = []string{
+	"text/*",
+	"application/json",
+	"application/vnd.api+json",
+	"application/problem+json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// CompressConfig is a configuration for the compression middleware.
+type CompressConfig struct {
+	// Level is the compression level from flate.HuffmanOnly to
+	// flate.BestCompression, defaults to the default compression.
+	Level int
+	// MinSize is the minimum response size in bytes to be compressed,
+	// defaults to 1024.
+	MinSize int
+	// ContentTypes is a list of compressible media types, "type/*" matches
+	// any subtype. Defaults to the common text formats.
+	ContentTypes []string
+}
+
+// Compress returns a middleware func that compresses responses with gzip or
+// deflate according to the Accept-Encoding header quality values. Responses
+// smaller than MinSize, of other content types, or already encoded are sent
+// as is, and so are partial content responses, whose byte ranges refer to the
+// identity encoding. Content-Length and Accept-Ranges are dropped from the
+// compressed responses, and strong ETags get the encoding suffix, such as
+// "abc-gzip", which is removed from the If-Match and If-None-Match request
+// headers, so that the handler compares its own ETags. Request bodies with
+// gzip Content-Encoding are decompressed transparently. Compress panics if
+// the compression level is invalid.
+func Compress(cfg CompressConfig) MiddlewareFunc {
+	c := newCompressor(cfg)
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+				zr, err := gzip.NewReader(r.Body)
+				if err != nil {
+					jsonapi.WriteError(w, http.StatusBadRequest, "invalid gzip request body")
+					return
+				}
+
+				r = r.Clone(r.Context())
+				r.Body = gzipBody{Reader: zr, body: r.Body}
+				r.ContentLength = -1
+				r.Header.Del("Content-Encoding")
+				r.Header.Del("Content-Length")
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := c.negotiate(r)
+			if encoding == "" || r.Method == http.MethodHead {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			r, conditional := stripEncodingETags(r, encoding)
+
+			cw := &compressWriter{ResponseWriter: w, c: c, encoding: encoding, conditional: conditional}
+			defer cw.close()
+
+			h.ServeHTTP(extend(cw, w), r)
+		}
+	}
+}
+
+type compressor struct {
+	minSize int
+	types   []string
+	pools   map[string]*sync.Pool
+}
+
+type encoder interface {
+	io.WriteCloser
+	Reset(io.Writer)
+	Flush() error
+}
+
+func newCompressor(cfg CompressConfig) *compressor {
+	if cfg.Level == 0 {
+		cfg.Level = flate.DefaultCompression
+	}
+
+	if cfg.Level < flate.HuffmanOnly || cfg.Level > flate.BestCompression {
+		panic("http: invalid compression level " + strconv.Itoa(cfg.Level))
+	}
+
+	if cfg.MinSize == 0 {
+		cfg.MinSize = defaultCompressMinSize
+	}
+
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = defaultCompressTypes
+	}
+
+	// The level is validated, so creating the writers does not fail.
+	level := cfg.Level
+
+	return &compressor{
+		minSize: cfg.MinSize,
+		types:   cfg.ContentTypes,
+		pools: map[string]*sync.Pool{
+			"gzip": {New: func() interface{} {
+				w, _ := gzip.NewWriterLevel(io.Discard, level)
+				return w
+			}},
+			"deflate": {New: func() interface{} {
+				w, _ := flate.NewWriter(io.Discard, level)
+				return w
+			}},
+		},
+	}
+}
+
+// negotiate returns the preferred supported encoding, gzip wins the ties.
+func (c *compressor) negotiate(r *http.Request) string {
+	values := qValues(strings.Join(r.Header.Values("Accept-Encoding"), ","))
+
+	var (
+		best      string
+		bestQ     float64
+		encodings = []string{"gzip", "deflate"}
+	)
+
+	for _, e := range encodings {
+		if q := encodingQuality(values, e); q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+
+	return best
+}
+
+func (c *compressor) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, t := range c.types {
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressWriter buffers the response until it is known whether it should be
+// compressed.
+type compressWriter struct {
+	http.ResponseWriter
+
+	c        *compressor
+	encoding string
+	enc      encoder
+	buf      []byte
+	status   int
+	decided  bool
+	flushed  bool
+	// conditional is set when the request validators had the encoding
+	// suffix, so that 304 Not Modified keeps it in the ETag.
+	conditional bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+
+	// Informational responses are sent immediately.
+	if code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.status = code
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.c.minSize {
+			return len(b), nil
+		}
+
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+
+		return len(b), nil
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide sends the header, choosing whether to compress the response, and
+// writes the buffered content.
+func (w *compressWriter) decide() error {
+	w.decided = true
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if w.status == http.StatusNotModified && w.conditional {
+		if etag := header.Get("ETag"); etag != "" {
+			header.Set("ETag", encodingETag(etag, w.encoding))
+		}
+	}
+
+	if w.shouldCompress() {
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		header.Set("Content-Encoding", w.encoding)
+
+		if etag := header.Get("ETag"); etag != "" {
+			header.Set("ETag", encodingETag(etag, w.encoding))
+		}
+
+		w.enc = w.c.pools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if w.enc != nil {
+		_, err := w.enc.Write(buf)
+		return err
+	}
+
+	_, err := w.ResponseWriter.Write(buf)
+
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	switch {
+	case w.status < http.StatusOK, w.status == http.StatusNoContent, w.status == http.StatusNotModified,
+		w.status == http.StatusPartialContent:
+		return false
+	case w.Header().Get("Content-Range") != "":
+		return false
+	case len(w.buf) < w.c.minSize && !w.flushed:
+		return false
+	case w.Header().Get("Content-Encoding") != "":
+		return false
+	default:
+		return w.c.compressible(w.Header().Get("Content-Type"))
+	}
+}
+
+// close sends the remaining buffered content and returns the encoder to the
+// pool.
+func (w *compressWriter) close() {
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			// Nothing was written, let the server send the default response.
+			return
+		}
+
+		if err := w.decide(); err != nil {
+			return
+		}
+	}
+
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(io.Discard)
+		w.c.pools[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
+
+func (w *compressWriter) flush() {
+	if !w.decided {
+		// Streaming responses are compressed regardless of the size.
+		w.flushed = true
+
+		if err := w.decide(); err != nil {
+			return
+		}
+	}
+
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *compressWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// encodingETag returns the strong ETag with the encoding suffix, so that the
+// compressed representation has a distinct strong validator. Weak ETags are
+// returned as is, as both representations are semantically equivalent.
+func encodingETag(etag, encoding string) string {
+	if strings.HasPrefix(etag, "W/") || len(etag) < 2 || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+
+	return etag[:len(etag)-1] + "-" + encoding + `"`
+}
+
+// stripEncodingETags removes the encoding suffix from the entity tags of the
+// If-Match and If-None-Match headers, and reports whether any was removed.
+func stripEncodingETags(r *http.Request, encoding string) (*http.Request, bool) {
+	suffix := "-" + encoding + `"`
+
+	var stripped bool
+
+	for _, key := range []string{"If-Match", "If-None-Match"} {
+		values := r.Header.Values(key)
+		if len(values) == 0 || !strings.Contains(strings.Join(values, ","), suffix) {
+			continue
+		}
+
+		if !stripped {
+			r = r.Clone(r.Context())
+			stripped = true
+		}
+
+		tags := strings.Split(strings.Join(values, ","), ",")
+		for i, tag := range tags {
+			tag = strings.TrimSpace(tag)
+			if strings.HasSuffix(tag, suffix) {
+				tag = tag[:len(tag)-len(suffix)] + `"`
+			}
+
+			tags[i] = tag
+		}
+
+		r.Header.Set(key, strings.Join(tags, ", "))
+	}
+
+	return r, stripped
+}
+
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
@@ -0,0 +1,276 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+)
+
+func TestCompress(t *testing.T) {
+	large := map[string]string{"data": strings.Repeat("a", 2048)}
+
+	tests := map[string]struct {
+		giveEncoding string
+		giveHandler  http.HandlerFunc
+		wantEncoding string
+		wantETag     string
+	}{
+		"gzip": {
+			"gzip, deflate",
+			func(w http.ResponseWriter, r *http.Request) {
+				jsonapi.Write(w, large)
+			},
+			"gzip",
+			"",
+		},
+		"deflate preferred by quality": {
+			"gzip;q=0.5, deflate",
+			func(w http.ResponseWriter, r *http.Request) {
+				jsonapi.Write(w, large)
+			},
+			"deflate",
+			"",
+		},
+		"wildcard": {
+			"*",
+			func(w http.ResponseWriter, r *http.Request) {
+				jsonapi.Write(w, large)
+			},
+			"gzip",
+			"",
+		},
+		"not accepted": {
+			"br, gzip;q=0",
+			func(w http.ResponseWriter, r *http.Request) {
+				jsonapi.Write(w, large)
+			},
+			"",
+			"",
+		},
+		"below min size": {
+			"gzip",
+			func(w http.ResponseWriter, r *http.Request) {
+				jsonapi.Write(w, map[string]string{"data": "a"})
+			},
+			"",
+			"",
+		},
+		"not compressible type": {
+			"gzip",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Write(bytes.Repeat([]byte{1}, 2048))
+			},
+			"",
+			"",
+		},
+		"already encoded": {
+			"gzip",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Content-Encoding", "br")
+				w.Write(bytes.Repeat([]byte{'a'}, 2048))
+			},
+			"br",
+			"",
+		},
+		"sniffed content type and encoding etag": {
+			"gzip",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"abc"`)
+				w.Write(bytes.Repeat([]byte{'a'}, 512))
+				w.Write(bytes.Repeat([]byte{'a'}, 1536))
+			},
+			"gzip",
+			`"abc-gzip"`,
+		},
+		"weak etag": {
+			"deflate",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `W/"abc"`)
+				w.Write(bytes.Repeat([]byte{'a'}, 2048))
+			},
+			"deflate",
+			`W/"abc"`,
+		},
+		"partial content": {
+			"gzip",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Content-Range", "bytes 0-2047/4096")
+				w.Write(bytes.Repeat([]byte{'a'}, 2048))
+			},
+			"",
+			"",
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			var want bytes.Buffer
+
+			wantRec := httptest.NewRecorder()
+			tc.giveHandler(wantRec, httptest.NewRequest("GET", "/", nil))
+			want.Write(wantRec.Body.Bytes())
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Encoding", tc.giveEncoding)
+
+			rec := httptest.NewRecorder()
+			Compress(CompressConfig{})(tc.giveHandler)(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.wantEncoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, []string{"Accept-Encoding"}, rec.Header().Values("Vary"))
+
+			if tc.wantETag != "" {
+				assert.Equal(t, tc.wantETag, rec.Header().Get("ETag"))
+			}
+
+			var body io.Reader = rec.Body
+
+			switch tc.wantEncoding {
+			case "gzip":
+				assert.Empty(t, rec.Header().Get("Content-Length"))
+
+				zr, err := gzip.NewReader(rec.Body)
+				require.NoError(t, err)
+
+				body = zr
+			case "deflate":
+				assert.Empty(t, rec.Header().Get("Content-Length"))
+
+				body = flate.NewReader(rec.Body)
+			}
+
+			got, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, want.String(), string(got))
+		})
+	}
+}
+
+func TestCompress_status(t *testing.T) {
+	h := Compress(CompressConfig{MinSize: 1})(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+}
+
+func TestCompress_flush(t *testing.T) {
+	h := Compress(CompressConfig{})(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+
+	got, _ := io.ReadAll(zr)
+	assert.Equal(t, "data: 1\n\n", string(got))
+}
+
+func TestCompress_requestBody(t *testing.T) {
+	var compressed bytes.Buffer
+
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(`{"name":"test"}`))
+	zw.Close()
+
+	var got map[string]string
+
+	h := Compress(CompressConfig{})(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, jsonapi.Read(r, &got))
+		assert.Empty(t, r.Header.Get("Content-Encoding"))
+	})
+
+	req := httptest.NewRequest("POST", "/", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]string{"name": "test"}, got)
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader("plain"))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	rec = httptest.NewRecorder()
+	h(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCompress_conditional(t *testing.T) {
+	var ifMatch string
+
+	h := Compress(CompressConfig{MinSize: 1})(func(w http.ResponseWriter, r *http.Request) {
+		ifMatch = r.Header.Get("If-Match")
+
+		w.Header().Set("ETag", `"abc"`)
+
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		io.WriteString(w, "content")
+	})
+
+	req := httptest.NewRequest("PUT", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-Match", `"other", "abc-gzip"`)
+
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	assert.Equal(t, `"other", "abc"`, ifMatch)
+	assert.Equal(t, `"abc-gzip"`, rec.Header().Get("ETag"))
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", `"abc-gzip"`)
+
+	rec = httptest.NewRecorder()
+	h(rec, req)
+
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, `"abc-gzip"`, rec.Header().Get("ETag"))
+}
+
+func TestCompress_InvalidLevel(t *testing.T) {
+	assert.PanicsWithValue(t, "http: invalid compression level 10", func() {
+		Compress(CompressConfig{Level: 10})
+	})
+}
@@ -1,19 +1,279 @@
 package http
 
-import "net/http"
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
 
-// CORS is a middleware func that allows cross-origin requests.
+// CORSConfig is a configuration for the cross-origin resource sharing.
+type CORSConfig struct {
+	// AllowedOrigins is a list of origins a cross-origin request can be
+	// executed from. An origin may be exact "https://example.com", with
+	// a wildcard subdomain "https://*.example.com" or "*" to allow all.
+	AllowedOrigins []string
+	// AllowedOriginPatterns are regular expressions the origin is matched
+	// against, in addition to AllowedOrigins.
+	AllowedOriginPatterns []*regexp.Regexp
+	// AllowOriginFunc is a custom origin validation func, in addition to
+	// AllowedOrigins and AllowedOriginPatterns.
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods is a list of methods allowed for cross-origin requests,
+	// defaults to GET, HEAD and POST. "*" allows any method.
+	AllowedMethods []string
+	// AllowedHeaders is a list of non-simple request headers allowed for
+	// cross-origin requests. "*" allows any header.
+	AllowedHeaders []string
+	// ExposedHeaders is a list of response headers exposed to the client.
+	ExposedHeaders []string
+	// AllowCredentials allows requests with credentials such as cookies.
+	// Allowed methods and headers are never responded with "*", as browsers
+	// reject it for credentialed requests. It cannot be combined with the
+	// "*" origin, which would grant credentialed access to every site.
+	AllowCredentials bool
+	// MaxAge is a duration the preflight response can be cached for.
+	MaxAge time.Duration
+}
+
+var permissiveCORS = NewCORS(CORSConfig{
+	AllowedOrigins: []string{"*"},
+	AllowedMethods: []string{"*"},
+	AllowedHeaders: []string{"*"},
+	ExposedHeaders: []string{"Location"},
+})
+
+// CORS is a middleware func that allows cross-origin requests from any
+// origin with any method and headers. Use NewCORS for a restrictive policy.
 func CORS(h http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "*")
-		w.Header().Set("Access-Control-Expose-Headers", "Location")
+	return permissiveCORS(h)
+}
+
+// NewCORS returns a middleware func that allows cross-origin requests
+// according to the given configuration. Preflight requests are responded
+// with 204 No Content, or 403 Forbidden if not allowed, and are not passed
+// to the handler. Other OPTIONS requests are handled as usual. NewCORS panics
+// if credentials are allowed for any origin.
+func NewCORS(cfg CORSConfig) MiddlewareFunc {
+	c := newCORS(cfg)
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				c.preflight(w, r)
+				return
+			}
 
-		if r.Method == "OPTIONS" {
-			return
+			c.actual(w, r)
+			h.ServeHTTP(w, r)
 		}
+	}
+}
+
+type cors struct {
+	cfg         CORSConfig
+	anyOrigin   bool
+	anyMethod   bool
+	anyHeader   bool
+	origins     []string
+	wildcards   [][2]string
+	methods     map[string]bool
+	headers     map[string]bool
+	exposed     string
+	allowMethod string
+	allowHeader string
+}
 
-		h.ServeHTTP(w, r)
+func newCORS(cfg CORSConfig) *cors {
+	c := &cors{
+		cfg:     cfg,
+		methods: make(map[string]bool),
+		headers: make(map[string]bool),
+		exposed: strings.Join(cfg.ExposedHeaders, ", "),
 	}
+
+	for _, o := range cfg.AllowedOrigins {
+		o = strings.ToLower(o)
+
+		switch i := strings.Index(o, "*"); {
+		case o == "*":
+			c.anyOrigin = true
+		case i >= 0:
+			c.wildcards = append(c.wildcards, [2]string{o[:i], o[i+1:]})
+		default:
+			c.origins = append(c.origins, o)
+		}
+	}
+
+	if c.anyOrigin && cfg.AllowCredentials {
+		panic(`http: CORS credentials cannot be allowed for the "*" origin`)
+	}
+
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+
+	for _, m := range methods {
+		if m == "*" {
+			c.anyMethod = true
+		}
+
+		c.methods[strings.ToUpper(m)] = true
+	}
+
+	for _, h := range cfg.AllowedHeaders {
+		if h == "*" {
+			c.anyHeader = true
+		}
+
+		c.headers[http.CanonicalHeaderKey(h)] = true
+	}
+
+	c.allowMethod = strings.Join(methods, ", ")
+	c.allowHeader = strings.Join(cfg.AllowedHeaders, ", ")
+
+	return c
+}
+
+// preflight responds to the preflight request.
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	reqHeaders := splitList(r.Header.Get("Access-Control-Request-Headers"))
+
+	if !c.allowOrigin(origin) || !c.allowMethods(method) || !c.allowHeaders(reqHeaders) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", c.originValue(origin))
+
+	if c.anyMethod {
+		header.Set("Access-Control-Allow-Methods", method)
+	} else {
+		header.Set("Access-Control-Allow-Methods", c.allowMethod)
+	}
+
+	switch {
+	case len(reqHeaders) == 0:
+	case c.anyHeader:
+		header.Set("Access-Control-Allow-Headers", strings.Join(reqHeaders, ", "))
+	default:
+		header.Set("Access-Control-Allow-Headers", c.allowHeader)
+	}
+
+	if c.cfg.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if c.cfg.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.cfg.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// actual sets the response headers for the actual cross-origin request.
+func (c *cors) actual(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+
+	// The response depends on the origin unless any origin gets the same "*".
+	if !c.anyOrigin {
+		header.Add("Vary", "Origin")
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.allowOrigin(origin) {
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", c.originValue(origin))
+
+	if c.cfg.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if c.exposed != "" {
+		header.Set("Access-Control-Expose-Headers", c.exposed)
+	}
+}
+
+func (c *cors) originValue(origin string) string {
+	if c.anyOrigin {
+		return "*"
+	}
+
+	return origin
+}
+
+func (c *cors) allowOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	if c.anyOrigin {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+
+	for _, o := range c.origins {
+		if o == lower {
+			return true
+		}
+	}
+
+	for _, w := range c.wildcards {
+		prefix, suffix := w[0], w[1]
+		if len(lower) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(lower, prefix) && strings.HasSuffix(lower, suffix) &&
+			!strings.ContainsAny(lower[len(prefix):len(lower)-len(suffix)], "/:") {
+			return true
+		}
+	}
+
+	for _, p := range c.cfg.AllowedOriginPatterns {
+		if p.MatchString(origin) {
+			return true
+		}
+	}
+
+	return c.cfg.AllowOriginFunc != nil && c.cfg.AllowOriginFunc(origin)
+}
+
+func (c *cors) allowMethods(method string) bool {
+	return c.anyMethod || c.methods[method]
+}
+
+func (c *cors) allowHeaders(headers []string) bool {
+	if c.anyHeader {
+		return true
+	}
+
+	for _, h := range headers {
+		if !c.headers[http.CanonicalHeaderKey(h)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func splitList(s string) []string {
+	var list []string
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
 }
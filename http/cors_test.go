@@ -0,0 +1,218 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCORS(t *testing.T) {
+	restricted := CORSConfig{
+		AllowedOrigins:        []string{"https://example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://[a-z]+\.test$`)},
+		AllowOriginFunc: func(origin string) bool {
+			return origin == "https://func.local"
+		},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"Content-Type", "X-Request-ID"},
+		ExposedHeaders:   []string{"Location", "ETag"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	tests := map[string]struct {
+		giveConfig  CORSConfig
+		giveMethod  string
+		giveHeader  http.Header
+		wantCode    int
+		wantHandled bool
+		wantHeader  http.Header
+	}{
+		"exact origin": {
+			restricted,
+			"GET",
+			http.Header{"Origin": {"https://example.com"}},
+			http.StatusOK,
+			true,
+			http.Header{
+				"Access-Control-Allow-Origin":      {"https://example.com"},
+				"Access-Control-Allow-Credentials": {"true"},
+				"Access-Control-Expose-Headers":    {"Location, ETag"},
+				"Vary":                             {"Origin"},
+			},
+		},
+		"wildcard subdomain": {
+			restricted,
+			"GET",
+			http.Header{"Origin": {"https://api.example.org"}},
+			http.StatusOK,
+			true,
+			http.Header{"Access-Control-Allow-Origin": {"https://api.example.org"}},
+		},
+		"wildcard subdomain mismatch": {
+			restricted,
+			"GET",
+			http.Header{"Origin": {"https://evil.com/.example.org"}},
+			http.StatusOK,
+			true,
+			http.Header{"Access-Control-Allow-Origin": nil},
+		},
+		"regex origin": {
+			restricted,
+			"GET",
+			http.Header{"Origin": {"https://abc.test"}},
+			http.StatusOK,
+			true,
+			http.Header{"Access-Control-Allow-Origin": {"https://abc.test"}},
+		},
+		"func origin": {
+			restricted,
+			"GET",
+			http.Header{"Origin": {"https://func.local"}},
+			http.StatusOK,
+			true,
+			http.Header{"Access-Control-Allow-Origin": {"https://func.local"}},
+		},
+		"disallowed origin": {
+			restricted,
+			"GET",
+			http.Header{"Origin": {"https://evil.com"}},
+			http.StatusOK,
+			true,
+			http.Header{
+				"Access-Control-Allow-Origin": nil,
+				"Vary":                        {"Origin"},
+			},
+		},
+		"preflight": {
+			restricted,
+			"OPTIONS",
+			http.Header{
+				"Origin":                         {"https://example.com"},
+				"Access-Control-Request-Method":  {"PUT"},
+				"Access-Control-Request-Headers": {"content-type"},
+			},
+			http.StatusNoContent,
+			false,
+			http.Header{
+				"Access-Control-Allow-Origin":      {"https://example.com"},
+				"Access-Control-Allow-Methods":     {"GET, PUT"},
+				"Access-Control-Allow-Headers":     {"Content-Type, X-Request-ID"},
+				"Access-Control-Allow-Credentials": {"true"},
+				"Access-Control-Max-Age":           {"600"},
+				"Vary":                             {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		"preflight disallowed method": {
+			restricted,
+			"OPTIONS",
+			http.Header{
+				"Origin":                        {"https://example.com"},
+				"Access-Control-Request-Method": {"DELETE"},
+			},
+			http.StatusForbidden,
+			false,
+			http.Header{"Access-Control-Allow-Origin": nil},
+		},
+		"preflight disallowed header": {
+			restricted,
+			"OPTIONS",
+			http.Header{
+				"Origin":                         {"https://example.com"},
+				"Access-Control-Request-Method":  {"GET"},
+				"Access-Control-Request-Headers": {"X-Secret"},
+			},
+			http.StatusForbidden,
+			false,
+			http.Header{"Access-Control-Allow-Origin": nil},
+		},
+		"plain options request": {
+			restricted,
+			"OPTIONS",
+			http.Header{"Origin": {"https://example.com"}},
+			http.StatusOK,
+			true,
+			http.Header{"Access-Control-Allow-Origin": {"https://example.com"}},
+		},
+		"any origin": {
+			CORSConfig{AllowedOrigins: []string{"*"}},
+			"GET",
+			http.Header{"Origin": {"https://example.com"}},
+			http.StatusOK,
+			true,
+			http.Header{
+				"Access-Control-Allow-Origin": {"*"},
+				"Vary":                        nil,
+			},
+		},
+		"any method and header preflight": {
+			CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"*"}, AllowedHeaders: []string{"*"}},
+			"OPTIONS",
+			http.Header{
+				"Origin":                         {"https://example.com"},
+				"Access-Control-Request-Method":  {"PATCH"},
+				"Access-Control-Request-Headers": {"X-Custom, X-Other"},
+			},
+			http.StatusNoContent,
+			false,
+			http.Header{
+				"Access-Control-Allow-Origin":  {"*"},
+				"Access-Control-Allow-Methods": {"PATCH"},
+				"Access-Control-Allow-Headers": {"X-Custom, X-Other"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			var handled bool
+
+			h := NewCORS(tc.giveConfig)(func(http.ResponseWriter, *http.Request) {
+				handled = true
+			})
+
+			req := httptest.NewRequest(tc.giveMethod, "/", nil)
+			req.Header = tc.giveHeader
+
+			rec := httptest.NewRecorder()
+			h(rec, req)
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantHandled, handled)
+
+			for k, v := range tc.wantHeader {
+				assert.Equal(t, v, rec.Header().Values(k), k)
+			}
+		})
+	}
+}
+
+func TestNewCORS_AnyOriginWithCredentials(t *testing.T) {
+	assert.PanicsWithValue(t, `http: CORS credentials cannot be allowed for the "*" origin`, func() {
+		NewCORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	})
+}
+
+func TestCORS(t *testing.T) {
+	var handled bool
+
+	h := CORS(func(http.ResponseWriter, *http.Request) {
+		handled = true
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Origin", "https://example.com")
+
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	assert.True(t, handled)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Location", rec.Header().Get("Access-Control-Expose-Headers"))
+}
@@ -0,0 +1,248 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+)
+
+// ErrUnknownCredential is an error when the credential is not found in the
+// store.
+var ErrUnknownCredential = errors.New("unknown credential")
+
+// unknownPasswordHash is compared against for unknown users, computed once
+// on the first use.
+var (
+	unknownPasswordHash []byte
+	unknownPasswordOnce sync.Once
+)
+
+// KeyLocation is the request location of the API key.
+type KeyLocation struct {
+	query bool
+	name  string
+}
+
+// InHeader returns the KeyLocation of the API key in the given header.
+func InHeader(name string) KeyLocation {
+	return KeyLocation{name: name}
+}
+
+// InQuery returns the KeyLocation of the API key in the given query parameter.
+func InQuery(name string) KeyLocation {
+	return KeyLocation{query: true, name: name}
+}
+
+func (l KeyLocation) get(r *http.Request) string {
+	if l.query {
+		return r.URL.Query().Get(l.name)
+	}
+
+	return r.Header.Get(l.name)
+}
+
+// APIKey returns the Authenticator that sets the API key at the given
+// location.
+func APIKey(loc KeyLocation, key string) Authenticator {
+	return apiKey{loc: loc, key: key}
+}
+
+type apiKey struct {
+	loc KeyLocation
+	key string
+}
+
+func (a apiKey) Authenticate(r *http.Request) error {
+	if !a.loc.query {
+		r.Header.Set(a.loc.name, a.key)
+		return nil
+	}
+
+	q := r.URL.Query()
+	q.Set(a.loc.name, a.key)
+	r.URL.RawQuery = q.Encode()
+
+	return nil
+}
+
+// BasicAuth returns the Authenticator that sets the basic authorization
+// header with the given user and password.
+func BasicAuth(user, pass string) Authenticator {
+	return basicAuth{user: user, pass: pass}
+}
+
+type basicAuth struct {
+	user string
+	pass string
+}
+
+func (a basicAuth) Authenticate(r *http.Request) error {
+	r.SetBasicAuth(a.user, a.pass)
+	return nil
+}
+
+// Credential is a stored credential. Secrets are never stored in plain text,
+// only their hashes produced with HashSecret for API keys, and with
+// HashPassword for basic auth passwords.
+type Credential struct {
+	// Principal identifies the authenticated client.
+	Principal string
+	// SecretHash is the hash of the secret.
+	SecretHash string
+}
+
+// CredentialStore is a pluggable storage of credentials.
+type CredentialStore interface {
+	// Lookup returns the credential by its identifier, which is the user
+	// name for basic auth, and the hash of the key for API keys. It returns
+	// ErrUnknownCredential if there is no such credential.
+	Lookup(ctx context.Context, id string) (Credential, error)
+}
+
+// StaticCredentials is a CredentialStore of fixed credentials by identifier.
+type StaticCredentials map[string]Credential
+
+// Lookup returns the credential by its identifier.
+func (s StaticCredentials) Lookup(_ context.Context, id string) (Credential, error) {
+	c, ok := s[id]
+	if !ok {
+		return Credential{}, ErrUnknownCredential
+	}
+
+	return c, nil
+}
+
+// HashSecret returns the hex encoded SHA-256 hash of the secret, such as an
+// API key, which is looked up by the hash. Secrets are expected to be random
+// and of high entropy, as the hash is not salted. Use HashPassword for
+// passwords.
+func HashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// HashPassword returns the salted bcrypt hash of the password, to be stored
+// for basic auth. It returns an error if the password is longer than 72
+// bytes.
+func HashPassword(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(h), nil
+}
+
+// RequireAPIKey returns a middleware func that authenticates requests with the
+// API key at the given location. The key is looked up in the store by its
+// hash. The principal of the credential is put into the request context.
+// Requests with a missing or unknown key are responded with 401 Unauthorized.
+func RequireAPIKey(loc KeyLocation, store CredentialStore) MiddlewareFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := loc.get(r)
+			if key == "" {
+				jsonapi.WriteError(w, http.StatusUnauthorized)
+				return
+			}
+
+			hash := HashSecret(key)
+
+			cred, err := store.Lookup(r.Context(), hash)
+			if err != nil {
+				writeCredentialError(w, err)
+				return
+			}
+
+			if !secretEqual(cred.SecretHash, hash) {
+				jsonapi.WriteError(w, http.StatusUnauthorized)
+				return
+			}
+
+			h.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), cred.Principal)))
+		}
+	}
+}
+
+// RequireBasicAuth returns a middleware func that authenticates requests with
+// the basic authorization credentials. The password is verified against the
+// bcrypt hash produced with HashPassword, also for unknown users, so that
+// the response time does not reveal whether the user exists. The principal of the credential is
+// put into the request context. Requests with missing or invalid credentials
+// are responded with 401 Unauthorized and the challenge for the realm.
+func RequireBasicAuth(realm string, store CredentialStore) MiddlewareFunc {
+	challenge := `Basic realm="` + realm + `", charset="UTF-8"`
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			if !ok {
+				w.Header().Set("WWW-Authenticate", challenge)
+				jsonapi.WriteError(w, http.StatusUnauthorized)
+
+				return
+			}
+
+			cred, err := store.Lookup(r.Context(), user)
+			if err != nil && !errors.Is(err, ErrUnknownCredential) {
+				writeCredentialError(w, err)
+				return
+			}
+
+			// The hash is compared even for unknown users, so that the
+			// response time does not reveal whether the user exists.
+			hash := []byte(cred.SecretHash)
+
+			known := err == nil
+			if !known {
+				unknownPasswordOnce.Do(func() {
+					unknownPasswordHash, _ = bcrypt.GenerateFromPassword(nil, bcrypt.DefaultCost)
+				})
+
+				hash = unknownPasswordHash
+			}
+
+			if bcrypt.CompareHashAndPassword(hash, []byte(pass)) != nil || !known {
+				w.Header().Set("WWW-Authenticate", challenge)
+				jsonapi.WriteError(w, http.StatusUnauthorized)
+
+				return
+			}
+
+			h.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), cred.Principal)))
+		}
+	}
+}
+
+// ContextWithPrincipal returns a copy of ctx carrying the authenticated
+// principal.
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal carried by ctx.
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	p, ok := ctx.Value(principalKey).(string)
+	return p, ok
+}
+
+func secretEqual(stored, given string) bool {
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(given)) == 1
+}
+
+func writeCredentialError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrUnknownCredential) {
+		jsonapi.WriteError(w, http.StatusUnauthorized)
+		return
+	}
+
+	jsonapi.WriteError(w, http.StatusInternalServerError)
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{}
+
+func (failingStore) Lookup(context.Context, string) (Credential, error) {
+	return Credential{}, assert.AnError
+}
+
+func TestAPIKey(t *testing.T) {
+	tests := map[string]struct {
+		giveLoc    KeyLocation
+		wantHeader string
+		wantQuery  string
+	}{
+		"header": {InHeader("X-API-Key"), "secret", ""},
+		"query":  {InQuery("api_key"), "", "secret"},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/?a=b", nil)
+			require.NoError(t, APIKey(tc.giveLoc, "secret").Authenticate(req))
+
+			assert.Equal(t, tc.wantHeader, req.Header.Get("X-API-Key"))
+			assert.Equal(t, tc.wantQuery, req.URL.Query().Get("api_key"))
+			assert.Equal(t, "b", req.URL.Query().Get("a"))
+		})
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, BasicAuth("user", "pass").Authenticate(req))
+
+	user, pass, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "pass", pass)
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	store := StaticCredentials{
+		HashSecret("secret"): {Principal: "service", SecretHash: HashSecret("secret")},
+	}
+
+	tests := map[string]struct {
+		giveStore CredentialStore
+		giveKey   string
+		wantCode  int
+		wantBody  string
+	}{
+		"valid":   {store, "secret", http.StatusOK, "service"},
+		"unknown": {store, "other", http.StatusUnauthorized, `{"errors":["Unauthorized"]}`},
+		"missing": {store, "", http.StatusUnauthorized, `{"errors":["Unauthorized"]}`},
+		"store error": {
+			failingStore{},
+			"secret",
+			http.StatusInternalServerError,
+			`{"errors":["Internal Server Error"]}`,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := RequireAPIKey(InHeader("X-API-Key"), tc.giveStore)(func(w http.ResponseWriter, r *http.Request) {
+				p, _ := PrincipalFromContext(r.Context())
+				io.WriteString(w, p)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, APIKey(InHeader("X-API-Key"), tc.giveKey).Authenticate(req))
+
+			rec := httptest.NewRecorder()
+			h(rec, req)
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+		})
+	}
+}
+
+func TestRequireBasicAuth(t *testing.T) {
+	hash, err := HashPassword("pass")
+	require.NoError(t, err)
+
+	store := StaticCredentials{
+		"user":   {Principal: "user@example.com", SecretHash: hash},
+		"legacy": {Principal: "legacy@example.com", SecretHash: HashSecret("pass")},
+	}
+
+	h := RequireBasicAuth("api", store)(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := PrincipalFromContext(r.Context())
+		io.WriteString(w, p)
+	})
+
+	tests := map[string]struct {
+		giveAuth      Authenticator
+		wantCode      int
+		wantBody      string
+		wantChallenge string
+	}{
+		"valid": {
+			BasicAuth("user", "pass"),
+			http.StatusOK,
+			"user@example.com",
+			"",
+		},
+		"wrong password": {
+			BasicAuth("user", "other"),
+			http.StatusUnauthorized,
+			`{"errors":["Unauthorized"]}`,
+			`Basic realm="api", charset="UTF-8"`,
+		},
+		"unsalted hash": {
+			BasicAuth("legacy", "pass"),
+			http.StatusUnauthorized,
+			`{"errors":["Unauthorized"]}`,
+			`Basic realm="api", charset="UTF-8"`,
+		},
+		"unknown user": {
+			BasicAuth("other", "pass"),
+			http.StatusUnauthorized,
+			`{"errors":["Unauthorized"]}`,
+			`Basic realm="api", charset="UTF-8"`,
+		},
+		"missing": {
+			fakeAuthenticator(func(*http.Request) error { return nil }),
+			http.StatusUnauthorized,
+			`{"errors":["Unauthorized"]}`,
+			`Basic realm="api", charset="UTF-8"`,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, tc.giveAuth.Authenticate(req))
+
+			rec := httptest.NewRecorder()
+			h(rec, req)
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+			assert.Equal(t, tc.wantChallenge, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	h1, err := HashPassword("pass")
+	require.NoError(t, err)
+
+	h2, err := HashPassword("pass")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, h1, h2, "hashes are salted")
+}
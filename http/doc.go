@@ -28,5 +28,134 @@ Basic Usage
 	authClient := auth.WithAuthenticator(http.DefaultClient, authenticator)
 
 	resp, err := authClient.Get("http://remote.resource/profiles")
+
+Request Correlation
+
+RequestID middleware assigns the X-Request-ID to incoming requests and
+WithRequestID forwards it on outgoing calls made with the request context.
+Client decorators wrap the existing transport, so they compose in any order.
+Retries share the request identifier of the original call.
+
+	mux.WithMiddleware(http.RequestID)
+
+	client := http.WithAuthenticator(http.DefaultClient, authenticator)
+	client = http.WithRequestID(client)
+	client = retry.WithPolicy(client, retry.DefaultPolicy())
+
+	func handle(w http.ResponseWriter, r *http.Request) {
+		log.WithContext(r.Context()).Infof("calling profiles")
+
+		req, _ := http.NewRequestWithContext(r.Context(), "GET", profilesURL, nil)
+		resp, err := client.Do(req)
+		...
+	}
+
+Client Logging
+
+WithLogging logs outbound requests and responses with the Debug level,
+including the DNS, connect, TLS and time to first byte durations. Credential
+headers and query parameters are always redacted, and bodies are captured up
+to the limit as they are read.
+
+	client := http.WithLogging(http.DefaultClient, log, http.ClientLogConfig{
+		MaxBodySize:   1024,
+		RedactHeaders: []string{"X-Api-Key"},
+		RedactQuery:   []string{"signature"},
+	})
+	client = http.WithAuthenticator(client, authenticator)
+
+Tracing
+
+Trace middleware continues the W3C trace context of incoming requests, and
+WithTracing propagates it to outgoing calls made with the request context.
+
+	mux.WithMiddleware(http.Trace(tracer))
+
+	client := http.WithTracing(http.DefaultClient, tracer)
+
+TLS
+
+TLSListener serves HTTPS with the certificate reloaded from files when they
+change or on SIGHUP. Setting the client CAs enables mutual TLS, and
+RequireClientCert puts the verified client identity into the request context.
+
+	clientCAs, _ := http.LoadCertPool("/etc/tls/clients-ca.crt")
+
+	l, err := http.NewTLSListener(&stdhttp.Server{Addr: ":8443", Handler: mux}, http.TLSConfig{
+		CertFile:  "/etc/tls/tls.crt",
+		KeyFile:   "/etc/tls/tls.key",
+		ClientCAs: clientCAs,
+	})
+
+	mux.WithMiddleware(http.RequireClientCert)
+
+	err = http.New(l).Serve(ctx)
+
+Routing
+
+Mux matches the request path against route patterns. Parameters can be
+constrained with an inline regular expression or a named type (int, uint,
+alpha, alnum, hex, slug, uuid), marked optional with a trailing "?", and
+the last segment may be a catch-all wildcard.
+
+	mux := &http.Mux{}
+
+	mux.AddRoute("GET", "/users/:id<int>", getUser)
+	mux.AddRoute("GET", "/posts/:year{[0-9]{4}}/:page<uint>?", listPosts)
+	mux.AddRoute("GET", "/files/*path", getFile)
+
+	func getUser(w http.ResponseWriter, r *http.Request) {
+		id := http.Params(r.Context())["id"]
+		...
+	}
+
+Routes can also be restricted to a host, with host labels captured as
+parameters, to required headers and to query parameters.
+
+	mux.AddRoute("GET", "/users", listUsersV2,
+		http.WithHost("{tenant}.api.example.com"),
+		http.WithHeader("Accept", "application/vnd.v2+json"),
+	)
+
+Token Verification
+
+The jwt package verifies bearer tokens of incoming requests against the keys
+of a JWKS endpoint and puts the claims into the request context. Required
+scopes are checked per route by wrapping the handler.
+
+	verifier := jwt.NewVerifier(jwt.Config{
+		Keys:     jwt.NewJWKS(jwt.JWKSConfig{URL: "https://auth.example.com/.well-known/jwks.json"}),
+		Issuer:   "https://auth.example.com/",
+		Audience: "profiles",
+		Leeway:   30 * time.Second,
+	})
+
+	mux.WithMiddleware(jwt.Middleware(verifier, log))
+	mux.AddRoute("DELETE", "/profiles/:id", jwt.RequireScopes("profiles:write")(deleteProfile))
+
+JSON:API Documents
+
+The jsonapi package marshals tagged structs into JSON:API 1.1 documents, with
+the related resources in included, and unmarshals the client payloads. The
+Negotiate middleware enforces the application/vnd.api+json media type.
+
+	type Article struct {
+		ID     string  `jsonapi:"primary,articles"`
+		Title  string  `jsonapi:"attr,title"`
+		Author *Person `jsonapi:"relation,author,omitempty"`
+	}
+
+	mux.AddRoute("POST", "/articles", jsonapi.Negotiate(createArticle))
+
+	func createArticle(w http.ResponseWriter, r *http.Request) {
+		var article Article
+
+		if err := jsonapi.ReadResource(r, &article); err != nil {
+			jsonapi.WriteErrors(w, jsonapi.StatusCode(err))
+			return
+		}
+
+		jsonapi.WriteResource(w, http.StatusCreated, &article)
+	}
 */
 package http
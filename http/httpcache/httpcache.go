@@ -0,0 +1,499 @@
+// Package httpcache provides a private HTTP cache for outgoing requests, as
+// described in RFC 7234, with pluggable storage backends.
+//
+// The cache transport should wrap the other client decorators, so that the
+// cache hits skip the retries, while the revalidation requests are retried
+// and authenticated as usual:
+//
+//	client := httpkit.WithAuthenticator(http.DefaultClient, authenticator)
+//	client = retry.WithPolicy(client, retry.DefaultPolicy())
+//	client = httpcache.WithCache(client, httpcache.NewLRU(64<<20))
+//
+// Since the cache is outside of the authenticator, the cached responses are
+// shared by all requests of the client regardless of their credentials.
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// XCacheHeader is the response header reporting how the response was served:
+// HIT, MISS, REVALIDATED or STALE.
+const XCacheHeader = "X-Cache"
+
+const (
+	cacheHit         = "HIT"
+	cacheMiss        = "MISS"
+	cacheRevalidated = "REVALIDATED"
+	cacheStale       = "STALE"
+)
+
+// cacheableStatus are the status codes cacheable by default.
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// WithCache returns a copy of http.Client with the caching transport. GET
+// responses with an explicit freshness lifetime (Cache-Control max-age or
+// Expires) or a validator (ETag or Last-Modified) are stored in the cache.
+// Fresh responses are served from the cache, and stale ones are revalidated
+// with a conditional request, or served while revalidated in background
+// within the stale-while-revalidate period. Responses varying on request
+// headers are matched against the request. Successful unsafe requests
+// invalidate the cached response of the URL. Responses too large to fit the
+// size limit of LRU or Disk cache once encoded, that is with the body over
+// three quarters of the limit, are passed through without being buffered.
+func WithCache(client *http.Client, c Cache) *http.Client {
+	cp := *client
+	if cp.Transport == nil {
+		cp.Transport = http.DefaultTransport
+	}
+
+	cp.Transport = &transport{
+		cache: c,
+		next:  cp.Transport,
+		now:   time.Now,
+	}
+
+	return &cp
+}
+
+type transport struct {
+	cache   Cache
+	next    http.RoundTripper
+	now     func() time.Time
+	pending sync.Map
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req)
+
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return t.next.RoundTrip(req)
+	default:
+		resp, err := t.next.RoundTrip(req)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			t.cache.Delete(req.Context(), key) // nolint:errcheck
+		}
+
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+
+	// Conditional and range requests are made by the caller on purpose.
+	if reqCC.has("no-store") || req.Header.Get("Range") != "" ||
+		req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	e, ok := t.load(req.Context(), key, req)
+	if !ok {
+		if reqCC.has("only-if-cached") {
+			return gatewayTimeout(req), nil
+		}
+
+		return t.fetch(req, key)
+	}
+
+	now := t.now()
+	age := e.age(now)
+	lifetime := e.lifetime()
+	respCC := parseCacheControl(e.Header)
+	noCache := reqCC.has("no-cache") || respCC.has("no-cache")
+
+	if maxAge, ok := reqCC.seconds("max-age"); ok && maxAge < lifetime {
+		lifetime = maxAge
+	}
+
+	if !noCache && age < lifetime {
+		return e.response(req, age, cacheHit), nil
+	}
+
+	if reqCC.has("only-if-cached") {
+		return gatewayTimeout(req), nil
+	}
+
+	if swr, ok := respCC.seconds("stale-while-revalidate"); ok && !noCache &&
+		!respCC.has("must-revalidate") && age < lifetime+swr {
+		t.revalidateAsync(req, key, e)
+		return e.response(req, age, cacheStale), nil
+	}
+
+	return t.revalidate(req, key, e)
+}
+
+func (t *transport) fetch(req *http.Request, key string) (*http.Response, error) {
+	reqTime := t.now()
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.store(req, key, resp, reqTime)
+}
+
+// revalidate sends the conditional request for the stored entry.
+func (t *transport) revalidate(req *http.Request, key string, e *entry) (*http.Response, error) {
+	r := req.Clone(req.Context())
+
+	if etag := e.Header.Get("ETag"); etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+
+	if lm := e.Header.Get("Last-Modified"); lm != "" {
+		r.Header.Set("If-Modified-Since", lm)
+	}
+
+	reqTime := t.now()
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusNotModified {
+		return t.store(req, key, resp, reqTime)
+	}
+
+	io.Copy(ioutil.Discard, resp.Body) // nolint:errcheck
+	resp.Body.Close()
+
+	for k, v := range resp.Header {
+		if k != "Content-Length" {
+			e.Header[k] = v
+		}
+	}
+
+	e.RequestTime = reqTime
+	e.ResponseTime = t.now()
+
+	t.save(req.Context(), key, e)
+
+	return e.response(req, e.age(e.ResponseTime), cacheRevalidated), nil
+}
+
+// revalidateAsync revalidates the entry in background, once per key at a
+// time.
+func (t *transport) revalidateAsync(req *http.Request, key string, e *entry) {
+	if _, running := t.pending.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	r := req.Clone(context.Background())
+	stored := e.clone()
+
+	go func() {
+		defer t.pending.Delete(key)
+
+		resp, err := t.revalidate(r, key, stored)
+		if err != nil {
+			return
+		}
+
+		io.Copy(ioutil.Discard, resp.Body) // nolint:errcheck
+		resp.Body.Close()
+	}()
+}
+
+// store saves the response if it is cacheable, and returns the response
+// with the body that can be read again.
+func (t *transport) store(req *http.Request, key string, resp *http.Response, reqTime time.Time) (*http.Response, error) {
+	resp.Header.Set(XCacheHeader, cacheMiss)
+
+	if !cacheable(resp) {
+		return resp, nil
+	}
+
+	limit := int64(-1)
+	if l, ok := t.cache.(sizeLimiter); ok {
+		// The body is stored base64 encoded in the JSON entry, so larger
+		// bodies cannot fit the limit.
+		limit = l.maxValueSize() / 4 * 3
+	}
+
+	if limit >= 0 && resp.ContentLength > limit {
+		t.cache.Delete(req.Context(), key) // nolint:errcheck
+		return resp, nil
+	}
+
+	body, err := readLimited(resp.Body, limit)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	if limit >= 0 && int64(len(body)) > limit {
+		t.cache.Delete(req.Context(), key) // nolint:errcheck
+
+		// The rest of the body is streamed to the caller.
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	e := &entry{
+		Status:       resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		Vary:         make(map[string]string),
+		RequestTime:  reqTime,
+		ResponseTime: t.now(),
+	}
+
+	e.Header.Del(XCacheHeader)
+
+	for _, field := range varyFields(resp.Header) {
+		e.Vary[field] = strings.Join(req.Header.Values(field), ",")
+	}
+
+	t.save(req.Context(), key, e)
+
+	return resp, nil
+}
+
+func (t *transport) load(ctx context.Context, key string, req *http.Request) (*entry, bool) {
+	b, ok, err := t.cache.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, false
+	}
+
+	for field, v := range e.Vary {
+		if strings.Join(req.Header.Values(field), ",") != v {
+			return nil, false
+		}
+	}
+
+	return &e, true
+}
+
+func (t *transport) save(ctx context.Context, key string, e *entry) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	// The cache is an optimization, failures to store are ignored.
+	t.cache.Set(ctx, key, b) // nolint:errcheck
+}
+
+// sizeLimiter is implemented by the caches not storing values over the size
+// limit.
+type sizeLimiter interface {
+	maxValueSize() int64
+}
+
+// readLimited reads the body up to one byte over the limit, or the whole
+// body if the limit is negative.
+func readLimited(body io.Reader, limit int64) ([]byte, error) {
+	if limit < 0 {
+		return ioutil.ReadAll(body)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(body, limit+1))
+}
+
+func cacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+func cacheable(resp *http.Response) bool {
+	cc := parseCacheControl(resp.Header)
+
+	if !cacheableStatus[resp.StatusCode] || cc.has("no-store") {
+		return false
+	}
+
+	for _, f := range varyFields(resp.Header) {
+		if f == "*" {
+			return false
+		}
+	}
+
+	_, maxAge := cc.seconds("max-age")
+	explicit := maxAge || resp.Header.Get("Expires") != ""
+	validator := resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+
+	return explicit || validator
+}
+
+func varyFields(h http.Header) []string {
+	var fields []string
+
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, http.CanonicalHeaderKey(f))
+			}
+		}
+	}
+
+	return fields
+}
+
+func gatewayTimeout(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout)),
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{XCacheHeader: {cacheMiss}},
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
+
+// entry is the stored response.
+type entry struct {
+	Status       int
+	Header       http.Header
+	Body         []byte
+	Vary         map[string]string
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+func (e *entry) clone() *entry {
+	cp := *e
+	cp.Header = e.Header.Clone()
+
+	return &cp
+}
+
+// lifetime returns the freshness lifetime of the response.
+func (e *entry) lifetime() time.Duration {
+	if maxAge, ok := parseCacheControl(e.Header).seconds("max-age"); ok {
+		return maxAge
+	}
+
+	if v := e.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			// Invalid dates represent a time in the past.
+			return 0
+		}
+
+		return expires.Sub(e.date())
+	}
+
+	return 0
+}
+
+// age returns the current age of the response.
+func (e *entry) age(now time.Time) time.Duration {
+	apparent := e.ResponseTime.Sub(e.date())
+	if apparent < 0 {
+		apparent = 0
+	}
+
+	corrected := e.ResponseTime.Sub(e.RequestTime)
+	if v, err := strconv.Atoi(e.Header.Get("Age")); err == nil {
+		corrected += time.Duration(v) * time.Second
+	}
+
+	if apparent > corrected {
+		corrected = apparent
+	}
+
+	return corrected + now.Sub(e.ResponseTime)
+}
+
+func (e *entry) date() time.Time {
+	if d, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return d
+	}
+
+	return e.ResponseTime
+}
+
+func (e *entry) response(req *http.Request, age time.Duration, status string) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(age/time.Second)))
+	header.Set(XCacheHeader, status)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cacheControl is the parsed Cache-Control header.
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) cacheControl {
+	cc := make(cacheControl)
+
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(d)
+			if d == "" {
+				continue
+			}
+
+			name, value, _ := strings.Cut(d, "=")
+			cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	v, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+
+	s, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || s < 0 {
+		return 0, false
+	}
+
+	return time.Duration(s) * time.Second, true
+}
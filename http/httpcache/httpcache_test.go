@@ -0,0 +1,323 @@
+package httpcache
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testServer struct {
+	*httptest.Server
+
+	hits    int32
+	mu      sync.Mutex
+	header  http.Header
+	etag    string
+	version int
+}
+
+func newTestServer(t *testing.T, header http.Header) *testServer {
+	t.Helper()
+
+	s := &testServer{header: header, etag: `"v1"`, version: 1}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.hits, 1)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for k, v := range s.header {
+			w.Header()[k] = v
+		}
+
+		// The Date header is suppressed, so that the age is computed from
+		// the fake clock of the client.
+		w.Header()["Date"] = nil
+
+		if r.Method != http.MethodGet {
+			s.version++
+			s.etag = fmt.Sprintf(`"v%d"`, s.version)
+
+			return
+		}
+
+		w.Header().Set("ETag", s.etag)
+
+		if r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		fmt.Fprintf(w, "version %d %s", s.version, r.Header.Get("Accept-Language"))
+	}))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *testServer) count() int {
+	return int(atomic.LoadInt32(&s.hits))
+}
+
+func (s *testServer) update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	s.etag = fmt.Sprintf(`"v%d"`, s.version)
+}
+
+func newClient(srv *testServer, c Cache) (*http.Client, *time.Time) {
+	now := time.Now()
+	client := WithCache(srv.Client(), c)
+	client.Transport.(*transport).now = func() time.Time { return now }
+
+	return client, &now
+}
+
+func get(t *testing.T, client *http.Client, url string, header ...string) (string, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body), resp.Header.Get(XCacheHeader)
+}
+
+func TestTransport_maxAge(t *testing.T) {
+	srv := newTestServer(t, http.Header{"Cache-Control": {"max-age=60"}})
+	client, now := newClient(srv, NewLRU(1<<20))
+
+	body, status := get(t, client, srv.URL)
+	assert.Equal(t, "version 1 ", body)
+	assert.Equal(t, cacheMiss, status)
+
+	srv.update()
+
+	body, status = get(t, client, srv.URL)
+	assert.Equal(t, "version 1 ", body, "fresh response is served from cache")
+	assert.Equal(t, cacheHit, status)
+	assert.Equal(t, 1, srv.count())
+
+	body, status = get(t, client, srv.URL, "Cache-Control", "no-cache")
+	assert.Equal(t, "version 2 ", body, "request no-cache forces revalidation")
+	assert.Equal(t, cacheMiss, status)
+
+	*now = now.Add(2 * time.Minute)
+
+	body, status = get(t, client, srv.URL)
+	assert.Equal(t, "version 2 ", body)
+	assert.Equal(t, cacheRevalidated, status, "stale response is revalidated with etag")
+	assert.Equal(t, 3, srv.count())
+}
+
+func TestTransport_etagRevalidation(t *testing.T) {
+	srv := newTestServer(t, http.Header{"Cache-Control": {"no-cache"}})
+	client, _ := newClient(srv, NewLRU(1<<20))
+
+	_, status := get(t, client, srv.URL)
+	assert.Equal(t, cacheMiss, status)
+
+	body, status := get(t, client, srv.URL)
+	assert.Equal(t, "version 1 ", body)
+	assert.Equal(t, cacheRevalidated, status)
+
+	srv.update()
+
+	body, status = get(t, client, srv.URL)
+	assert.Equal(t, "version 2 ", body)
+	assert.Equal(t, cacheMiss, status)
+	assert.Equal(t, 3, srv.count())
+}
+
+func TestTransport_expires(t *testing.T) {
+	expires := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	srv := newTestServer(t, http.Header{"Expires": {expires}})
+	client, now := newClient(srv, NewLRU(1<<20))
+
+	get(t, client, srv.URL)
+
+	_, status := get(t, client, srv.URL)
+	assert.Equal(t, cacheHit, status)
+
+	*now = now.Add(2 * time.Minute)
+
+	_, status = get(t, client, srv.URL)
+	assert.Equal(t, cacheRevalidated, status)
+}
+
+func TestTransport_vary(t *testing.T) {
+	srv := newTestServer(t, http.Header{
+		"Cache-Control": {"max-age=60"},
+		"Vary":          {"Accept-Language"},
+	})
+	client, _ := newClient(srv, NewLRU(1<<20))
+
+	body, _ := get(t, client, srv.URL, "Accept-Language", "en")
+	assert.Equal(t, "version 1 en", body)
+
+	body, status := get(t, client, srv.URL, "Accept-Language", "en")
+	assert.Equal(t, "version 1 en", body)
+	assert.Equal(t, cacheHit, status)
+
+	body, status = get(t, client, srv.URL, "Accept-Language", "de")
+	assert.Equal(t, "version 1 de", body)
+	assert.Equal(t, cacheMiss, status)
+}
+
+func TestTransport_staleWhileRevalidate(t *testing.T) {
+	srv := newTestServer(t, http.Header{"Cache-Control": {"max-age=60, stale-while-revalidate=60"}})
+	client, now := newClient(srv, NewLRU(1<<20))
+
+	get(t, client, srv.URL)
+	srv.update()
+
+	*now = now.Add(90 * time.Second)
+
+	body, status := get(t, client, srv.URL)
+	assert.Equal(t, "version 1 ", body)
+	assert.Equal(t, cacheStale, status)
+
+	assert.Eventually(t, func() bool {
+		body, status = get(t, client, srv.URL)
+		return status == cacheHit && body == "version 2 "
+	}, time.Second, 10*time.Millisecond, "response is revalidated in background")
+
+	*now = now.Add(10 * time.Minute)
+
+	body, status = get(t, client, srv.URL)
+	assert.Equal(t, "version 2 ", body)
+	assert.Equal(t, cacheRevalidated, status, "response beyond the stale period is revalidated")
+}
+
+func TestTransport_notCacheable(t *testing.T) {
+	tests := map[string]http.Header{
+		"no-store":       {"Cache-Control": {"no-store, max-age=60"}},
+		"vary all":       {"Cache-Control": {"max-age=60"}, "Vary": {"*"}},
+		"no freshness":   {},
+		"invalid maxage": {"Cache-Control": {"max-age=abc"}},
+	}
+
+	for name, test := range tests {
+		header := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newTestServer(t, header)
+
+			cache := NewLRU(1 << 20)
+			client, _ := newClient(srv, cache)
+
+			// The server always sets ETag, which is removed for the test.
+			client.Transport.(*transport).next = stripETag{srv.Client().Transport}
+
+			get(t, client, srv.URL)
+			get(t, client, srv.URL)
+
+			assert.Equal(t, 2, srv.count())
+			assert.Equal(t, 0, cache.Len())
+		})
+	}
+}
+
+func TestTransport_invalidation(t *testing.T) {
+	srv := newTestServer(t, http.Header{"Cache-Control": {"max-age=60"}})
+	client, _ := newClient(srv, NewLRU(1<<20))
+
+	get(t, client, srv.URL)
+
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("update"))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	body, status := get(t, client, srv.URL)
+	assert.Equal(t, "version 2 ", body)
+	assert.Equal(t, cacheMiss, status)
+}
+
+func TestTransport_onlyIfCached(t *testing.T) {
+	srv := newTestServer(t, http.Header{"Cache-Control": {"max-age=60"}})
+	client, _ := newClient(srv, NewLRU(1<<20))
+
+	resp, err := client.Get(srv.URL + "/other")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Cache-Control", "only-if-cached")
+
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Equal(t, 1, srv.count())
+}
+
+func TestTransport_disk(t *testing.T) {
+	srv := newTestServer(t, http.Header{"Cache-Control": {"max-age=60"}})
+
+	disk, err := NewDisk(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+
+	client, _ := newClient(srv, disk)
+	get(t, client, srv.URL)
+
+	// A new client shares the responses stored on disk.
+	client, _ = newClient(srv, disk)
+
+	body, status := get(t, client, srv.URL)
+	assert.Equal(t, "version 1 ", body)
+	assert.Equal(t, cacheHit, status)
+	assert.Equal(t, 1, srv.count())
+}
+
+func TestTransport_tooLarge(t *testing.T) {
+	srv := newTestServer(t, http.Header{"Cache-Control": {"max-age=60"}})
+	client, _ := newClient(srv, NewLRU(4))
+
+	for i := 1; i <= 2; i++ {
+		body, status := get(t, client, srv.URL)
+		assert.Equal(t, "version 1 ", body, "body over the limit is streamed")
+		assert.Equal(t, cacheMiss, status)
+		assert.Equal(t, i, srv.count())
+	}
+}
+
+type stripETag struct {
+	next http.RoundTripper
+}
+
+func (s stripETag) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := s.next.RoundTrip(req)
+	if err == nil {
+		resp.Header.Del("ETag")
+	}
+
+	return resp, err
+}
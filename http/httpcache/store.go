@@ -0,0 +1,328 @@
+package httpcache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// tempPrefix is the name prefix of the files being written by Disk.
+const tempPrefix = ".tmp-"
+
+// Cache is a pluggable storage of the serialized responses.
+type Cache interface {
+	// Get returns the value stored for the key, and false if there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value for the key.
+	Set(ctx context.Context, key string, value []byte) error
+	// Delete removes the value stored for the key.
+	Delete(ctx context.Context, key string) error
+}
+
+// LRU is the in-memory Cache that evicts the least recently used values when
+// the total size exceeds the limit.
+type LRU struct {
+	maxBytes int64
+	size     int64
+	items    map[string]*list.Element
+	order    *list.List
+	mu       sync.Mutex
+}
+
+type lruItem struct {
+	key   string
+	value []byte
+}
+
+// NewLRU returns a new instance of LRU cache holding up to maxBytes of
+// values.
+func NewLRU(maxBytes int64) *LRU {
+	return &LRU{
+		maxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the value stored for the key.
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(el)
+
+	return el.Value.(*lruItem).value, true, nil
+}
+
+// Set stores the value for the key. Values larger than the limit are not
+// stored.
+func (c *LRU) Set(_ context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(key)
+
+	if int64(len(value)) > c.maxBytes {
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&lruItem{key: key, value: value})
+	c.size += int64(len(value))
+
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back().Value.(*lruItem).key)
+	}
+
+	return nil
+}
+
+// maxValueSize returns the size of the largest value that can be stored.
+func (c *LRU) maxValueSize() int64 {
+	return c.maxBytes
+}
+
+// Delete removes the value stored for the key.
+func (c *LRU) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(key)
+
+	return nil
+}
+
+// Len returns the number of stored values.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.items)
+}
+
+func (c *LRU) remove(key string) {
+	el, ok := c.items[key]
+	if !ok {
+		return
+	}
+
+	c.order.Remove(el)
+	delete(c.items, key)
+	c.size -= int64(len(el.Value.(*lruItem).value))
+}
+
+// Disk is the Cache storing values as files in the directory, named by the
+// hex encoded SHA-256 hash of the key. The oldest stored values are evicted
+// when the total size exceeds the limit. Other files in the directory are
+// neither counted nor evicted.
+type Disk struct {
+	dir      string
+	maxBytes int64
+	size     int64
+	mu       sync.Mutex
+}
+
+// NewDisk returns a new instance of Disk cache in the given directory holding
+// up to maxBytes of values. The directory is created if it does not exist.
+func NewDisk(dir string, maxBytes int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	c := &Disk{dir: dir, maxBytes: maxBytes}
+
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		c.size += f.Size()
+	}
+
+	return c, nil
+}
+
+// Get returns the value stored for the key.
+func (c *Disk) Get(_ context.Context, key string) ([]byte, bool, error) {
+	b, err := ioutil.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return b, true, nil
+}
+
+// Set stores the value for the key. The file is replaced atomically, so that
+// concurrent readers never see a partial value. Values larger than the limit
+// are not stored.
+func (c *Disk) Set(ctx context.Context, key string, value []byte) error {
+	if int64(len(value)) > c.maxBytes {
+		return c.Delete(ctx, key)
+	}
+
+	f, err := ioutil.TempFile(c.dir, tempPrefix)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	replaced := fileSize(c.path(key))
+
+	if err := os.Rename(f.Name(), c.path(key)); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	c.size += int64(len(value)) - replaced
+
+	if c.size > c.maxBytes {
+		return c.evict()
+	}
+
+	return nil
+}
+
+// Delete removes the value stored for the key.
+func (c *Disk) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := fileSize(c.path(key))
+
+	err := os.Remove(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.size -= size
+
+	return nil
+}
+
+// maxValueSize returns the size of the largest value that can be stored.
+func (c *Disk) maxValueSize() int64 {
+	return c.maxBytes
+}
+
+// evict removes the least recently stored values until the total size is
+// within the limit. The size is recounted from the files, as the directory
+// may be shared with other instances. c.mu must be held.
+func (c *Disk) evict() error {
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	c.size = 0
+	for _, f := range files {
+		c.size += f.Size()
+	}
+
+	for _, f := range files {
+		if c.size <= c.maxBytes {
+			break
+		}
+
+		if err := os.Remove(filepath.Join(c.dir, f.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		c.size -= f.Size()
+	}
+
+	return nil
+}
+
+// files returns the stored values, skipping the ones being written and the
+// files not named by the cache.
+func (c *Disk) files() ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]os.FileInfo, 0, len(entries))
+
+	for _, e := range entries {
+		if e.IsDir() || !cacheFileName(e.Name()) {
+			continue
+		}
+
+		info, err := e.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, info)
+	}
+
+	return files, nil
+}
+
+func (c *Disk) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:]))
+}
+
+// cacheFileName reports whether the name is the hex encoded SHA-256 hash.
+func cacheFileName(name string) bool {
+	if len(name) != 2*sha256.Size {
+		return false
+	}
+
+	_, err := hex.DecodeString(name)
+
+	return err == nil
+}
+
+// fileSize returns the size of the file, or zero if it does not exist.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
@@ -0,0 +1,99 @@
+package httpcache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1234")))
+	require.NoError(t, c.Set(ctx, "b", []byte("1234")))
+
+	_, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok)
+
+	require.NoError(t, c.Set(ctx, "c", []byte("1234")))
+
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok, "least recently used is evicted")
+
+	v, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1234"), v)
+
+	require.NoError(t, c.Set(ctx, "big", make([]byte, 11)))
+	assert.Equal(t, 2, c.Len(), "values over the limit are not stored")
+
+	require.NoError(t, c.Delete(ctx, "a"))
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestDisk(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewDisk(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+
+	_, ok, err := c.Get(ctx, "http://example.com/a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, c.Set(ctx, "http://example.com/a", []byte("value")))
+
+	v, ok, err := c.Get(ctx, "http://example.com/a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), v)
+
+	require.NoError(t, c.Delete(ctx, "http://example.com/a"))
+	require.NoError(t, c.Delete(ctx, "http://example.com/a"))
+
+	_, ok, _ = c.Get(ctx, "http://example.com/a")
+	assert.False(t, ok)
+}
+
+func TestDisk_evict(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	unrelated := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(unrelated, make([]byte, 100), 0o600))
+
+	c, err := NewDisk(dir, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), c.size, "other files are not counted")
+
+	old := time.Now().Add(-time.Hour)
+
+	for i, key := range []string{"a", "b"} {
+		require.NoError(t, c.Set(ctx, key, []byte("1234")))
+		require.NoError(t, os.Chtimes(c.path(key), old, old.Add(time.Duration(i)*time.Minute)))
+	}
+
+	require.NoError(t, c.Set(ctx, "c", []byte("1234")))
+
+	_, ok, _ := c.Get(ctx, "a")
+	assert.False(t, ok, "oldest value is evicted")
+
+	_, ok, _ = c.Get(ctx, "b")
+	assert.True(t, ok)
+
+	require.NoError(t, c.Set(ctx, "big", make([]byte, 11)))
+
+	_, ok, _ = c.Get(ctx, "big")
+	assert.False(t, ok, "values over the limit are not stored")
+
+	c, err = NewDisk(dir, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), c.size, "size is counted from the stored files")
+	assert.FileExists(t, unrelated, "other files are not evicted")
+}
@@ -0,0 +1,339 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+	"github.com/diptanw/go-toolkit/storage"
+)
+
+// IdempotencyKeyHeader is the header carrying the client idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	defaultIdempotencyTTL  = 24 * time.Hour
+	defaultIdempotencyBody = 1 << 20
+	maxIdempotencyKeyLen   = 255
+	idempotencySweep       = time.Minute
+)
+
+// IdempotencyRecord is the stored state of the request with the idempotency
+// key.
+type IdempotencyRecord struct {
+	Key string
+	// Fingerprint identifies the request method, path and body.
+	Fingerprint string
+	// Completed is false while the request is in flight.
+	Completed bool
+	Status    int
+	Header    http.Header
+	Body      []byte
+	Expires   time.Time
+}
+
+// ID returns the record key.
+func (r IdempotencyRecord) ID() storage.ID {
+	return storage.ID(r.Key)
+}
+
+// IdempotencyStore is a pluggable storage of the idempotency records.
+type IdempotencyStore interface {
+	// Begin atomically stores the in-flight record if there is no record
+	// with the same key yet. Otherwise, it returns the existing record and
+	// false.
+	Begin(ctx context.Context, rec IdempotencyRecord) (IdempotencyRecord, bool, error)
+	// Complete replaces the in-flight record with the completed one.
+	Complete(ctx context.Context, rec IdempotencyRecord) error
+	// Release removes the record, so that the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyConfig is a configuration for the idempotency middleware.
+type IdempotencyConfig struct {
+	// Store stores the records, defaults to a new InMemoryIdempotency.
+	Store IdempotencyStore
+	// TTL is the time the responses are replayed for, defaults to 24 hours.
+	TTL time.Duration
+	// Methods are the request methods the keys are honoured for, defaults to
+	// POST and PATCH.
+	Methods []string
+	// MaxBodySize is the maximum size of the request body in bytes read to
+	// fingerprint the request, defaults to 1 MiB.
+	MaxBodySize int64
+}
+
+// Idempotency returns a middleware func that makes requests with the
+// Idempotency-Key header safe to retry. The first response for the key is
+// recorded and replayed for the repeated requests with Idempotent-Replayed
+// header. Requests repeated while the first one is in flight are responded
+// with 409 Conflict, and requests with the same key but a different method,
+// path or body with 422 Unprocessable Entity. Server errors are not recorded,
+// so the request can be retried. Keys are scoped by the principal of the
+// authenticated client, if any. Only the headers set by the wrapped handler
+// are recorded, and they do not overwrite the headers already set for the
+// repeated request, such as X-Request-ID. Requests with a body larger than
+// MaxBodySize are responded with 413 Request Entity Too Large.
+func Idempotency(cfg IdempotencyConfig) MiddlewareFunc {
+	if cfg.Store == nil {
+		cfg.Store = NewInMemoryIdempotency()
+	}
+
+	if cfg.TTL == 0 {
+		cfg.TTL = defaultIdempotencyTTL
+	}
+
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = defaultIdempotencyBody
+	}
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !contains(cfg.Methods, r.Method) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLen {
+				jsonapi.WriteError(w, http.StatusBadRequest, "idempotency key is too long")
+				return
+			}
+
+			if r.ContentLength > cfg.MaxBodySize {
+				jsonapi.WriteError(w, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &maxBytesReader{ReadCloser: r.Body, n: cfg.MaxBodySize}
+			}
+
+			body, err := readBody(r)
+
+			switch {
+			case errors.Is(err, jsonapi.ErrBodyTooLarge):
+				jsonapi.WriteError(w, http.StatusRequestEntityTooLarge)
+				return
+			case err != nil:
+				jsonapi.WriteError(w, http.StatusBadRequest, "read request body")
+				return
+			}
+
+			if principal, ok := PrincipalFromContext(r.Context()); ok {
+				key = principal + ":" + key
+			}
+
+			rec := IdempotencyRecord{
+				Key:         key,
+				Fingerprint: sha256Hex([]byte(r.Method + " " + r.URL.RequestURI() + "\n" + string(body))),
+				Expires:     time.Now().Add(cfg.TTL),
+			}
+
+			existing, created, err := cfg.Store.Begin(r.Context(), rec)
+			if err != nil {
+				jsonapi.WriteError(w, http.StatusInternalServerError)
+				return
+			}
+
+			if !created {
+				replay(w, existing, rec.Fingerprint)
+				return
+			}
+
+			serveIdempotent(w, r, h, cfg.Store, rec)
+		}
+	}
+}
+
+func replay(w http.ResponseWriter, rec IdempotencyRecord, fingerprint string) {
+	switch {
+	case rec.Fingerprint != fingerprint:
+		jsonapi.WriteError(w, http.StatusUnprocessableEntity, "idempotency key is reused with a different request")
+	case !rec.Completed:
+		w.Header().Set("Retry-After", "1")
+		jsonapi.WriteError(w, http.StatusConflict, "request with the idempotency key is in progress")
+	default:
+		// The headers of the repeated request, such as the request ID or
+		// rate limits, are kept.
+		header := w.Header()
+		for k, v := range rec.Header {
+			if _, ok := header[k]; !ok {
+				header[k] = append([]string(nil), v...)
+			}
+		}
+
+		header.Set("Idempotent-Replayed", "true")
+		w.WriteHeader(rec.Status)
+		w.Write(rec.Body) // nolint:errcheck
+	}
+}
+
+func serveIdempotent(w http.ResponseWriter, r *http.Request, h http.HandlerFunc, store IdempotencyStore,
+	rec IdempotencyRecord) {
+	bw := &bodyRecorder{responseWriter: &responseWriter{ResponseWriter: w}}
+	before := w.Header().Clone()
+
+	// The key is released if the handler panics or fails, so that the
+	// client can retry.
+	completed := false
+
+	defer func() {
+		if !completed {
+			store.Release(context.Background(), rec.Key) // nolint:errcheck
+		}
+	}()
+
+	h.ServeHTTP(extend(bw, w), r)
+
+	if bw.Status() >= http.StatusInternalServerError {
+		return
+	}
+
+	rec.Completed = true
+	rec.Status = bw.Status()
+	rec.Header = handlerHeader(before, w.Header())
+	rec.Body = bw.body.Bytes()
+
+	completed = store.Complete(context.Background(), rec) == nil
+}
+
+// handlerHeader returns the header fields added or changed since the before
+// snapshot, that is the ones set by the handler.
+func handlerHeader(before, after http.Header) http.Header {
+	header := make(http.Header)
+
+	for k, v := range after {
+		if prev, ok := before[k]; ok && equalValues(prev, v) {
+			continue
+		}
+
+		header[k] = append([]string(nil), v...)
+	}
+
+	return header
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bodyRecorder records the response status and copies the body written
+// through the writer.
+type bodyRecorder struct {
+	*responseWriter
+
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	n, err := w.responseWriter.Write(b)
+	w.body.Write(b[:n])
+
+	return n, err
+}
+
+// InMemoryIdempotency is the IdempotencyStore backed by storage.InMemory,
+// expired records are evicted periodically.
+type InMemoryIdempotency struct {
+	records   *storage.InMemory[IdempotencyRecord]
+	lastSweep time.Time
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+// NewInMemoryIdempotency returns a new instance of InMemoryIdempotency.
+func NewInMemoryIdempotency() *InMemoryIdempotency {
+	return &InMemoryIdempotency{
+		records: storage.NewInMemory[IdempotencyRecord](),
+		now:     time.Now,
+	}
+}
+
+// Begin stores the in-flight record if there is no record with the same key.
+// An expired record is replaced, and the records are changed under the lock
+// only, so that the expired record is not replaced concurrently between its
+// read, removal and the insert of the new one.
+func (s *InMemoryIdempotency) Begin(_ context.Context, rec IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	existing, err := s.records.Get(rec.ID())
+
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+	case err != nil:
+		return IdempotencyRecord{}, false, err
+	case !now.After(existing.Expires):
+		return existing, false, nil
+	default:
+		if err := s.records.Remove(existing.ID()); err != nil {
+			return IdempotencyRecord{}, false, err
+		}
+	}
+
+	if err := s.records.Insert(rec); err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+
+	return rec, true, nil
+}
+
+// Complete replaces the in-flight record with the completed one.
+func (s *InMemoryIdempotency) Complete(_ context.Context, rec IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.records.Update(rec)
+}
+
+// Release removes the record.
+func (s *InMemoryIdempotency) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.records.Remove(storage.ID(key))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// sweep removes the expired records, s.mu must be held.
+func (s *InMemoryIdempotency) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) <= idempotencySweep {
+		return
+	}
+
+	s.lastSweep = now
+
+	s.records.Range(func(r IdempotencyRecord) bool {
+		if now.After(r.Expires) {
+			s.records.Remove(r.ID()) // nolint:errcheck
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	mw := Idempotency(IdempotencyConfig{})
+	h := mw(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "created %d: %s", calls, body)
+	})
+
+	serve := func(method, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+
+		rec := httptest.NewRecorder()
+		h(rec, req)
+
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "k1", "a")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "created 1: a", rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+
+	rec = serve(http.MethodPost, "k1", "a")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "created 1: a", rec.Body.String(), "response is replayed")
+	assert.Equal(t, "/orders/1", rec.Header().Get("Location"))
+	assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+
+	rec = serve(http.MethodPost, "k1", "b")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = serve(http.MethodPost, "k2", "b")
+	assert.Equal(t, "created 2: b", rec.Body.String())
+
+	rec = serve(http.MethodPost, "", "b")
+	assert.Equal(t, "created 3: b", rec.Body.String(), "requests without key are not recorded")
+
+	rec = serve(http.MethodPut, "k1", "a")
+	assert.Equal(t, "created 4: a", rec.Body.String(), "other methods are not recorded")
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	h := Idempotency(IdempotencyConfig{})(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	newReq := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("a"))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+
+		return req
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		h(httptest.NewRecorder(), newReq())
+		close(done)
+	}()
+
+	<-started
+
+	rec := httptest.NewRecorder()
+	h(rec, newReq())
+
+	close(release)
+	<-done
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+}
+
+func TestIdempotency_ServerError(t *testing.T) {
+	calls := 0
+	h := Idempotency(IdempotencyConfig{})(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	for _, want := range []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+
+		rec := httptest.NewRecorder()
+		h(rec, req)
+
+		assert.Equal(t, want, rec.Code)
+	}
+
+	assert.Equal(t, 2, calls, "failed request is retried, succeeded one is replayed")
+}
+
+func TestIdempotency_ReplayHeaders(t *testing.T) {
+	calls := 0
+	h := Idempotency(IdempotencyConfig{})(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for i, id := range []string{"r1", "r2"} {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("a"))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+
+		rec := httptest.NewRecorder()
+		rec.Header().Set(RequestIDHeader, id)
+		rec.Header().Set("RateLimit-Remaining", strconv.Itoa(9-i))
+		h(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/orders/1", rec.Header().Get("Location"))
+		assert.Equal(t, id, rec.Header().Get(RequestIDHeader), "request headers are not replayed")
+		assert.Equal(t, strconv.Itoa(9-i), rec.Header().Get("RateLimit-Remaining"))
+	}
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	h := Idempotency(IdempotencyConfig{MaxBodySize: 4})(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler must not be called")
+	})
+
+	for name, body := range map[string]io.Reader{
+		"known length":   strings.NewReader("abcde"),
+		"unknown length": io.MultiReader(strings.NewReader("abcde")),
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/orders", body)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+
+		rec := httptest.NewRecorder()
+		h(rec, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, name)
+	}
+}
+
+func TestInMemoryIdempotency_Begin(t *testing.T) {
+	now := time.Now()
+	s := NewInMemoryIdempotency()
+	s.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	rec := IdempotencyRecord{Key: "k1", Fingerprint: "f1", Expires: now.Add(time.Minute)}
+
+	_, created, err := s.Begin(ctx, rec)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	existing, created, err := s.Begin(ctx, IdempotencyRecord{Key: "k1", Fingerprint: "f2"})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "f1", existing.Fingerprint)
+
+	now = now.Add(2 * time.Minute)
+
+	_, created, err = s.Begin(ctx, IdempotencyRecord{Key: "k1", Expires: now.Add(time.Minute)})
+	require.NoError(t, err)
+	assert.True(t, created, "expired record is replaced")
+
+	require.NoError(t, s.Release(ctx, "k1"))
+
+	_, created, err = s.Begin(ctx, IdempotencyRecord{Key: "k1", Expires: now.Add(time.Minute)})
+	require.NoError(t, err)
+	assert.True(t, created, "released key can be reused")
+}
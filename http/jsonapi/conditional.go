@@ -0,0 +1,148 @@
+package jsonapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Validators are the resource validators for conditional requests.
+type Validators struct {
+	// ETag is the quoted entity tag, optionally prefixed with "W/" for weak
+	// tags.
+	ETag string
+	// LastModified is the last modification time of the resource.
+	LastModified time.Time
+}
+
+// ETag returns the strong entity tag computed from the JSON representation of
+// v.
+func ETag(v interface{}) (string, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return contentETag(content), nil
+}
+
+// WriteConditional writes a JSON representation of v to response, along with
+// the ETag and Last-Modified headers. The ETag is computed from the content
+// if the validators do not have one. If the request If-None-Match, or
+// If-Modified-Since in its absence, shows that the client has the current
+// representation, 304 Not Modified is written without the body.
+func WriteConditional(w http.ResponseWriter, r *http.Request, v interface{}, val Validators) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if val.ETag == "" {
+		val.ETag = contentETag(content)
+	}
+
+	setValidators(w, val)
+
+	if notModified(r, val) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeContent(w, http.StatusOK, jsonContentType, content)
+}
+
+// CheckPreconditions evaluates the If-Match and If-Unmodified-Since request
+// headers, and If-None-Match for unsafe methods, against the current
+// validators of the resource. If a precondition fails, 412 Precondition
+// Failed is written and false is returned. It is meant for updates with
+// optimistic concurrency control.
+func CheckPreconditions(w http.ResponseWriter, r *http.Request, current Validators) bool {
+	if !preconditionsMet(r, current) {
+		WriteError(w, http.StatusPreconditionFailed)
+		return false
+	}
+
+	return true
+}
+
+func preconditionsMet(r *http.Request, val Validators) bool {
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, val.ETag, false) {
+			return false
+		}
+	} else if ius, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && !val.LastModified.IsZero() {
+		if val.LastModified.Truncate(time.Second).After(ius) {
+			return false
+		}
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if inm := r.Header.Get("If-None-Match"); inm != "" && matchETag(inm, val.ETag, true) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func notModified(r *http.Request, val Validators) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchETag(inm, val.ETag, true)
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || val.LastModified.IsZero() {
+		return false
+	}
+
+	return !val.LastModified.Truncate(time.Second).After(ims)
+}
+
+// matchETag reports whether the comma separated list of entity tags in the
+// header matches the tag, using the weak or strong comparison.
+func matchETag(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return etag != ""
+	}
+
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+
+		if weak {
+			if strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+
+			continue
+		}
+
+		if t == etag && !strings.HasPrefix(t, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setValidators(w http.ResponseWriter, val Validators) {
+	if val.ETag != "" {
+		w.Header().Set("ETag", val.ETag)
+	}
+
+	if !val.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", val.LastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+func contentETag(content []byte) string {
+	h := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
@@ -0,0 +1,181 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteConditional(t *testing.T) {
+	modified := time.Date(2022, 6, 1, 12, 0, 0, 500, time.UTC)
+	resource := map[string]string{"name": "test"}
+
+	etag, err := ETag(resource)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		giveMethod  string
+		giveHeaders map[string]string
+		giveETag    string
+		wantCode    int
+		wantETag    string
+	}{
+		"no conditions": {
+			http.MethodGet,
+			nil,
+			"",
+			http.StatusOK,
+			etag,
+		},
+		"if-none-match matches": {
+			http.MethodGet,
+			map[string]string{"If-None-Match": `"other", ` + etag},
+			"",
+			http.StatusNotModified,
+			etag,
+		},
+		"if-none-match weak comparison": {
+			http.MethodGet,
+			map[string]string{"If-None-Match": `W/"v1"`},
+			`"v1"`,
+			http.StatusNotModified,
+			`"v1"`,
+		},
+		"if-none-match differs": {
+			http.MethodGet,
+			map[string]string{"If-None-Match": `"other"`},
+			"",
+			http.StatusOK,
+			etag,
+		},
+		"if-none-match takes precedence": {
+			http.MethodGet,
+			map[string]string{
+				"If-None-Match":     `"other"`,
+				"If-Modified-Since": modified.Format(http.TimeFormat),
+			},
+			"",
+			http.StatusOK,
+			etag,
+		},
+		"not modified since": {
+			http.MethodGet,
+			map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)},
+			"",
+			http.StatusNotModified,
+			etag,
+		},
+		"modified since": {
+			http.MethodGet,
+			map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)},
+			"",
+			http.StatusOK,
+			etag,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tc.giveMethod, "/", nil)
+			for k, v := range tc.giveHeaders {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			WriteConditional(rec, req, resource, Validators{ETag: tc.giveETag, LastModified: modified})
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantETag, rec.Header().Get("ETag"))
+			assert.Equal(t, "Wed, 01 Jun 2022 12:00:00 GMT", rec.Header().Get("Last-Modified"))
+
+			if tc.wantCode == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+			} else {
+				assert.JSONEq(t, `{"name":"test"}`, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	current := Validators{ETag: `"v2"`, LastModified: modified}
+
+	tests := map[string]struct {
+		giveMethod  string
+		giveHeaders map[string]string
+		wantOK      bool
+	}{
+		"no conditions": {
+			http.MethodPut,
+			nil,
+			true,
+		},
+		"if-match current": {
+			http.MethodPut,
+			map[string]string{"If-Match": `"v1", "v2"`},
+			true,
+		},
+		"if-match stale": {
+			http.MethodPut,
+			map[string]string{"If-Match": `"v1"`},
+			false,
+		},
+		"if-match weak tag": {
+			http.MethodPut,
+			map[string]string{"If-Match": `W/"v2"`},
+			false,
+		},
+		"if-match any": {
+			http.MethodDelete,
+			map[string]string{"If-Match": "*"},
+			true,
+		},
+		"unmodified since": {
+			http.MethodPatch,
+			map[string]string{"If-Unmodified-Since": modified.Format(http.TimeFormat)},
+			true,
+		},
+		"modified since": {
+			http.MethodPatch,
+			map[string]string{"If-Unmodified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)},
+			false,
+		},
+		"if-none-match any on update": {
+			http.MethodPut,
+			map[string]string{"If-None-Match": "*"},
+			false,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tc.giveMethod, "/", nil)
+			for k, v := range tc.giveHeaders {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			ok := CheckPreconditions(rec, req, current)
+
+			assert.Equal(t, tc.wantOK, ok)
+
+			if !tc.wantOK {
+				assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+				assert.JSONEq(t, `{"errors":["Precondition Failed"]}`, rec.Body.String())
+			}
+		})
+	}
+}
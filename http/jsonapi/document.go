@@ -0,0 +1,140 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Version is the supported JSON:API specification version.
+const Version = "1.1"
+
+// Document is the JSON:API top-level document. It contains either the
+// primary data or the errors.
+type Document struct {
+	Data     *Data         `json:"data,omitempty"`
+	Errors   []ErrorObject `json:"errors,omitempty"`
+	Meta     Meta          `json:"meta,omitempty"`
+	Links    Links         `json:"links,omitempty"`
+	Included []*Resource   `json:"included,omitempty"`
+	JSONAPI  *Object       `json:"jsonapi,omitempty"`
+}
+
+// Object describes the server implementation.
+type Object struct {
+	Version string   `json:"version,omitempty"`
+	Ext     []string `json:"ext,omitempty"`
+	Profile []string `json:"profile,omitempty"`
+	Meta    Meta     `json:"meta,omitempty"`
+}
+
+// Data is the primary data of the document or the relationship linkage. It is
+// a single resource, which may be null, or an array of resources.
+type Data struct {
+	One    *Resource
+	Many   []*Resource
+	IsMany bool
+}
+
+// MarshalJSON encodes the data as an object, null or an array.
+func (d Data) MarshalJSON() ([]byte, error) {
+	if d.IsMany {
+		if d.Many == nil {
+			return []byte("[]"), nil
+		}
+
+		return json.Marshal(d.Many)
+	}
+
+	return json.Marshal(d.One)
+}
+
+// UnmarshalJSON decodes the data from an object, null or an array.
+func (d *Data) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		d.IsMany = true
+		return json.Unmarshal(b, &d.Many)
+	}
+
+	return json.Unmarshal(b, &d.One)
+}
+
+// Resource is the resource object, or the resource identifier object when it
+// has no attributes, relationships and links. Either ID or the local ID is
+// set, except for the resources created with the server generated ID.
+type Resource struct {
+	Type          string                     `json:"type"`
+	ID            string                     `json:"id,omitempty"`
+	LID           string                     `json:"lid,omitempty"`
+	Attributes    map[string]json.RawMessage `json:"attributes,omitempty"`
+	Relationships map[string]*Relationship   `json:"relationships,omitempty"`
+	Links         Links                      `json:"links,omitempty"`
+	Meta          Meta                       `json:"meta,omitempty"`
+}
+
+// Relationship is the relationship object, with the resource linkage in
+// data.
+type Relationship struct {
+	Data  *Data `json:"data,omitempty"`
+	Links Links `json:"links,omitempty"`
+	Meta  Meta  `json:"meta,omitempty"`
+}
+
+// Meta is the non-standard meta-information.
+type Meta map[string]interface{}
+
+// Links is the links object, keyed by the link relation such as "self".
+type Links map[string]Link
+
+// Link is the link, encoded as a string when it has only the URL.
+type Link struct {
+	Href        string `json:"href"`
+	Rel         string `json:"rel,omitempty"`
+	DescribedBy string `json:"describedby,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Type        string `json:"type,omitempty"`
+	HrefLang    string `json:"hreflang,omitempty"`
+	Meta        Meta   `json:"meta,omitempty"`
+}
+
+// link is the Link without the custom marshaling.
+type link Link
+
+// MarshalJSON encodes the link as a string or an object.
+func (l Link) MarshalJSON() ([]byte, error) {
+	if l.Rel == "" && l.DescribedBy == "" && l.Title == "" && l.Type == "" && l.HrefLang == "" && l.Meta == nil {
+		return json.Marshal(l.Href)
+	}
+
+	return json.Marshal(link(l))
+}
+
+// UnmarshalJSON decodes the link from a string or an object.
+func (l *Link) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '"' {
+		*l = Link{}
+		return json.Unmarshal(b, &l.Href)
+	}
+
+	return json.Unmarshal(b, (*link)(l))
+}
+
+// ErrorObject is the error object of the errors document.
+type ErrorObject struct {
+	ID     string       `json:"id,omitempty"`
+	Links  Links        `json:"links,omitempty"`
+	Status string       `json:"status,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Title  string       `json:"title,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+	Source *ErrorSource `json:"source,omitempty"`
+	Meta   Meta         `json:"meta,omitempty"`
+}
+
+// ErrorSource references the cause of the error in the request.
+type ErrorSource struct {
+	// Pointer is the JSON Pointer to the value in the request document, such
+	// as "/data/attributes/title".
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Header    string `json:"header,omitempty"`
+}
@@ -2,21 +2,54 @@ package jsonapi
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 )
 
+const jsonContentType = "application/json; charset=utf-8"
+
+// ErrBodyTooLarge is an error when the request body exceeds the size limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// ReadOption is a func that configures reading of the request body.
+type ReadOption func(*readOptions)
+
+type readOptions struct {
+	maxBytes int64
+}
+
+// WithMaxBytes limits the size of the request body to n bytes. Read returns
+// ErrBodyTooLarge if the body is larger.
+func WithMaxBytes(n int64) ReadOption {
+	return func(o *readOptions) {
+		o.maxBytes = n
+	}
+}
+
 // Write writes a JSON representation of v to response.
 func Write(w http.ResponseWriter, v interface{}) {
+	WriteStatus(w, http.StatusOK, v)
+}
+
+// WriteStatus writes a JSON representation of v to response with the given
+// status code.
+func WriteStatus(w http.ResponseWriter, status int, v interface{}) {
 	content, err := json.Marshal(v)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	writeContent(w, status, jsonContentType, content)
+}
+
+func writeContent(w http.ResponseWriter, status int, contentType string, content []byte) {
 	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
 
 	if _, err := w.Write(content); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -24,9 +57,35 @@ func Write(w http.ResponseWriter, v interface{}) {
 	}
 }
 
+// WriteError writes an ErrorResponse with the given status code and error
+// messages. The status text is used if no messages are given.
+func WriteError(w http.ResponseWriter, status int, msgs ...string) {
+	if len(msgs) == 0 {
+		msgs = []string{http.StatusText(status)}
+	}
+
+	WriteStatus(w, status, ErrorResponse{Errors: msgs})
+}
+
 // Read unmarshals the JSON-encoded data into v.
-func Read(r *http.Request, v interface{}) error {
-	body, err := ioutil.ReadAll(r.Body)
+func Read(r *http.Request, v interface{}, opts ...ReadOption) error {
+	var o readOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.maxBytes > 0 && r.ContentLength > o.maxBytes {
+		r.Body.Close()
+		return ErrBodyTooLarge
+	}
+
+	var reader io.Reader = r.Body
+	if o.maxBytes > 0 {
+		reader = io.LimitReader(r.Body, o.maxBytes+1)
+	}
+
+	body, err := ioutil.ReadAll(reader)
 	if err := r.Body.Close(); err != nil {
 		return err
 	}
@@ -35,5 +94,33 @@ func Read(r *http.Request, v interface{}) error {
 		return err
 	}
 
+	if o.maxBytes > 0 && int64(len(body)) > o.maxBytes {
+		return ErrBodyTooLarge
+	}
+
 	return json.Unmarshal(body, v)
 }
+
+// StatusCode returns the HTTP status code for the error returned by Read:
+// 413 Request Entity Too Large if the body exceeds the limit, 400 Bad Request
+// if the body is not a valid JSON for the value or an invalid document, 409
+// Conflict if the resource type does not match, and 500 Internal Server Error
+// otherwise.
+func StatusCode(err error) int {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrTypeConflict):
+		return http.StatusConflict
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, ErrInvalidDocument):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,634 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Errors returned by Unmarshal.
+var (
+	// ErrInvalidDocument is an error when the document does not match the
+	// target value.
+	ErrInvalidDocument = errors.New("invalid document")
+	// ErrTypeConflict is an error when the resource type does not match the
+	// type of the target value.
+	ErrTypeConflict = errors.New("resource type conflict")
+)
+
+// LinksProvider is implemented by the tagged structs providing the resource
+// links.
+type LinksProvider interface {
+	ResourceLinks() Links
+}
+
+// MetaProvider is implemented by the tagged structs providing the resource
+// meta-information.
+type MetaProvider interface {
+	ResourceMeta() Meta
+}
+
+// RelationshipLinksProvider is implemented by the tagged structs providing
+// the links of their relationships.
+type RelationshipLinksProvider interface {
+	RelationshipLinks(name string) Links
+}
+
+type fieldKind int
+
+const (
+	fieldAttr fieldKind = iota
+	fieldRelation
+	fieldLID
+)
+
+type structField struct {
+	index     int
+	kind      fieldKind
+	name      string
+	omitempty bool
+}
+
+// structInfo is the JSON:API mapping of the struct fields, declared by the
+// `jsonapi` struct tags:
+//
+//	ID     string    `jsonapi:"primary,articles"`
+//	LID    string    `jsonapi:"lid"`
+//	Title  string    `jsonapi:"attr,title,omitempty"`
+//	Author *Person   `jsonapi:"relation,author"`
+//	Tags   []*Tag    `jsonapi:"relation,tags,omitempty"`
+type structInfo struct {
+	typ    string
+	id     int
+	fields []structField
+}
+
+var structCache sync.Map
+
+func inspect(t reflect.Type) (*structInfo, error) {
+	if info, ok := structCache.Load(t); ok {
+		return info.(*structInfo), nil
+	}
+
+	info := &structInfo{id: -1}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag, ok := sf.Tag.Lookup("jsonapi")
+		if !ok {
+			continue
+		}
+
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("jsonapi: unexported field %s.%s", t, sf.Name)
+		}
+
+		parts := strings.Split(tag, ",")
+
+		switch {
+		case parts[0] == "primary" && len(parts) == 2:
+			info.id = i
+			info.typ = parts[1]
+		case parts[0] == "lid" && len(parts) == 1:
+			info.fields = append(info.fields, structField{index: i, kind: fieldLID})
+		case (parts[0] == "attr" || parts[0] == "relation") && len(parts) >= 2 && parts[1] != "":
+			f := structField{index: i, kind: fieldAttr, name: parts[1]}
+			if parts[0] == "relation" {
+				f.kind = fieldRelation
+			}
+
+			f.omitempty = len(parts) == 3 && parts[2] == "omitempty"
+			info.fields = append(info.fields, f)
+		default:
+			return nil, fmt.Errorf("jsonapi: invalid tag %q of %s.%s", tag, t, sf.Name)
+		}
+
+		if err := checkKind(parts[0], sf.Type.Kind()); err != nil {
+			return nil, fmt.Errorf("jsonapi: %s.%s %w", t, sf.Name, err)
+		}
+	}
+
+	if info.id < 0 {
+		return nil, fmt.Errorf("jsonapi: no primary field in %s", t)
+	}
+
+	structCache.Store(t, info)
+
+	return info, nil
+}
+
+// checkKind returns an error if the field kind cannot be used for the tag.
+func checkKind(tag string, kind reflect.Kind) error {
+	switch tag {
+	case "primary":
+		switch kind {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return fmt.Errorf("must be a string or an integer, not %s", kind)
+		}
+	case "lid":
+		if kind != reflect.String {
+			return fmt.Errorf("must be a string, not %s", kind)
+		}
+	case "relation":
+		switch kind {
+		case reflect.Ptr, reflect.Slice, reflect.Interface:
+		default:
+			return fmt.Errorf("must be a pointer, slice or interface, not %s", kind)
+		}
+	}
+
+	return nil
+}
+
+// Marshal returns the document with the primary data of v, which is a
+// pointer to a struct with the `jsonapi` tags, or a slice of them. The
+// related resources having any attribute or relationship set are added to
+// the included resources, other ones are only referenced by identifiers.
+func Marshal(v interface{}) (*Document, error) {
+	m := marshaler{seen: make(map[string]bool)}
+	data := &Data{}
+
+	rv := reflect.ValueOf(v)
+
+	switch {
+	case rv.Kind() == reflect.Slice:
+		data.IsMany = true
+		data.Many = make([]*Resource, 0, rv.Len())
+
+		for i := 0; i < rv.Len(); i++ {
+			res, err := m.primary(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			data.Many = append(data.Many, res)
+		}
+	case rv.Kind() == reflect.Ptr && rv.IsNil():
+	default:
+		res, err := m.primary(rv)
+		if err != nil {
+			return nil, err
+		}
+
+		data.One = res
+	}
+
+	// Related resources are included in the breadth-first order, and may
+	// queue their own related resources.
+	for i := 0; i < len(m.queue); i++ {
+		res, err := m.resource(m.queue[i])
+		if err != nil {
+			return nil, err
+		}
+
+		m.included = append(m.included, res)
+	}
+
+	return &Document{
+		Data:     data,
+		Included: m.included,
+		JSONAPI:  &Object{Version: Version},
+	}, nil
+}
+
+type marshaler struct {
+	seen     map[string]bool
+	queue    []reflect.Value
+	included []*Resource
+}
+
+func (m *marshaler) primary(rv reflect.Value) (*Resource, error) {
+	res, err := m.resource(rv)
+	if err != nil {
+		return nil, err
+	}
+
+	m.seen[resourceKey(res)] = true
+
+	return res, nil
+}
+
+func (m *marshaler) resource(rv reflect.Value) (*Resource, error) {
+	sv := reflect.Indirect(rv)
+	if sv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("jsonapi: cannot marshal %s", rv.Type())
+	}
+
+	info, err := inspect(sv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := formatID(sv.Field(info.id))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Resource{Type: info.typ, ID: id}
+
+	for _, f := range info.fields {
+		fv := sv.Field(f.index)
+
+		switch f.kind {
+		case fieldLID:
+			res.LID = fv.String()
+		case fieldAttr:
+			if f.omitempty && fv.IsZero() {
+				continue
+			}
+
+			b, err := json.Marshal(fv.Interface())
+			if err != nil {
+				return nil, fmt.Errorf("jsonapi: marshal attribute %s: %w", f.name, err)
+			}
+
+			if res.Attributes == nil {
+				res.Attributes = make(map[string]json.RawMessage)
+			}
+
+			res.Attributes[f.name] = b
+		case fieldRelation:
+			if f.omitempty && emptyRelation(fv) {
+				continue
+			}
+
+			rel, err := m.relationship(fv)
+			if err != nil {
+				return nil, fmt.Errorf("jsonapi: marshal relationship %s: %w", f.name, err)
+			}
+
+			if p, ok := provider(rv).(RelationshipLinksProvider); ok {
+				rel.Links = p.RelationshipLinks(f.name)
+			}
+
+			if res.Relationships == nil {
+				res.Relationships = make(map[string]*Relationship)
+			}
+
+			res.Relationships[f.name] = rel
+		}
+	}
+
+	if p, ok := provider(rv).(LinksProvider); ok {
+		res.Links = p.ResourceLinks()
+	}
+
+	if p, ok := provider(rv).(MetaProvider); ok {
+		res.Meta = p.ResourceMeta()
+	}
+
+	return res, nil
+}
+
+// emptyRelation reports whether the relation field is nil or an empty slice.
+func emptyRelation(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice:
+		return fv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return fv.IsNil()
+	default:
+		return false
+	}
+}
+
+func (m *marshaler) relationship(fv reflect.Value) (*Relationship, error) {
+	if fv.Kind() != reflect.Slice {
+		if emptyRelation(fv) {
+			return &Relationship{Data: &Data{}}, nil
+		}
+
+		id, err := m.identifier(fv)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Relationship{Data: &Data{One: id}}, nil
+	}
+
+	data := &Data{IsMany: true, Many: make([]*Resource, 0, fv.Len())}
+
+	for i := 0; i < fv.Len(); i++ {
+		id, err := m.identifier(fv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+
+		data.Many = append(data.Many, id)
+	}
+
+	return &Relationship{Data: data}, nil
+}
+
+// identifier returns the identifier of the related resource, and queues it
+// for inclusion.
+func (m *marshaler) identifier(rv reflect.Value) (*Resource, error) {
+	if rv.Kind() == reflect.Interface && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	sv := reflect.Indirect(rv)
+	if sv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot marshal %s", rv.Type())
+	}
+
+	info, err := inspect(sv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := formatID(sv.Field(info.id))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Resource{Type: info.typ, ID: id}
+
+	for _, f := range info.fields {
+		if f.kind == fieldLID {
+			res.LID = sv.Field(f.index).String()
+		}
+	}
+
+	if key := resourceKey(res); !m.seen[key] && hasContent(sv, info) {
+		m.seen[key] = true
+		m.queue = append(m.queue, rv)
+	}
+
+	return res, nil
+}
+
+// hasContent reports whether the resource has any attribute or relationship
+// set, so that it is worth including.
+func hasContent(sv reflect.Value, info *structInfo) bool {
+	for _, f := range info.fields {
+		if f.kind != fieldLID && !sv.Field(f.index).IsZero() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// provider returns the value for the provider interface assertions, which
+// may be implemented with pointer receivers.
+func provider(rv reflect.Value) interface{} {
+	if rv.Kind() != reflect.Ptr && rv.CanAddr() {
+		rv = rv.Addr()
+	}
+
+	return rv.Interface()
+}
+
+func resourceKey(res *Resource) string {
+	if res.ID == "" {
+		return res.Type + "/lid/" + res.LID
+	}
+
+	return res.Type + "/" + res.ID
+}
+
+// formatID returns the ID of the primary field, which is empty for the zero
+// value, so that new resources have no ID.
+func formatID(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() == 0 {
+			return "", nil
+		}
+
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() == 0 {
+			return "", nil
+		}
+
+		return strconv.FormatUint(v.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("jsonapi: unsupported primary field type %s", v.Type())
+	}
+}
+
+func parseID(s string, v reflect.Value) error {
+	if s == "" {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: invalid id %q", ErrInvalidDocument, s)
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: invalid id %q", ErrInvalidDocument, s)
+		}
+
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("jsonapi: unsupported primary field type %s", v.Type())
+	}
+
+	return nil
+}
+
+// Unmarshal decodes the primary data of the document into v, which is a
+// pointer to a struct with the `jsonapi` tags, or a pointer to a slice of
+// them. Related resources are populated from the included resources, or set
+// with the identifiers only.
+func Unmarshal(doc *Document, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("jsonapi: cannot unmarshal into %T", v)
+	}
+
+	if doc.Data == nil {
+		return fmt.Errorf("%w: missing data", ErrInvalidDocument)
+	}
+
+	u := unmarshaler{
+		included: make(map[string]*Resource, len(doc.Included)),
+		values:   make(map[string]reflect.Value),
+	}
+
+	for _, res := range doc.Included {
+		u.included[resourceKey(res)] = res
+	}
+
+	target := rv.Elem()
+
+	if target.Kind() == reflect.Slice {
+		if !doc.Data.IsMany {
+			return fmt.Errorf("%w: data is not an array", ErrInvalidDocument)
+		}
+
+		slice := reflect.MakeSlice(target.Type(), 0, len(doc.Data.Many))
+
+		for i, res := range doc.Data.Many {
+			ev := reflect.New(target.Type().Elem()).Elem()
+			if err := u.value(res, ev, fmt.Sprintf("/data/%d", i)); err != nil {
+				return err
+			}
+
+			slice = reflect.Append(slice, ev)
+		}
+
+		target.Set(slice)
+
+		return nil
+	}
+
+	if doc.Data.IsMany || doc.Data.One == nil {
+		return fmt.Errorf("%w: data is not a resource object", ErrInvalidDocument)
+	}
+
+	return u.resource(doc.Data.One, target, "/data")
+}
+
+type unmarshaler struct {
+	included map[string]*Resource
+	// values are the related resources decoded so far, so that cyclic
+	// relationships share the values.
+	values map[string]reflect.Value
+}
+
+// value decodes the resource into a struct or a pointer to a struct.
+func (u unmarshaler) value(res *Resource, v reflect.Value, pointer string) error {
+	if v.Kind() != reflect.Ptr {
+		return u.resource(res, v, pointer)
+	}
+
+	if res == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	ptr := reflect.New(v.Type().Elem())
+	if err := u.resource(res, ptr.Elem(), pointer); err != nil {
+		return err
+	}
+
+	v.Set(ptr)
+
+	return nil
+}
+
+func (u unmarshaler) resource(res *Resource, sv reflect.Value, pointer string) error {
+	if sv.Kind() != reflect.Struct {
+		return fmt.Errorf("jsonapi: cannot unmarshal into %s", sv.Type())
+	}
+
+	info, err := inspect(sv.Type())
+	if err != nil {
+		return err
+	}
+
+	if res.Type != info.typ {
+		return fmt.Errorf("%w: %s/type is %q, not %q", ErrTypeConflict, pointer, res.Type, info.typ)
+	}
+
+	if err := parseID(res.ID, sv.Field(info.id)); err != nil {
+		return err
+	}
+
+	for _, f := range info.fields {
+		fv := sv.Field(f.index)
+
+		switch f.kind {
+		case fieldLID:
+			fv.SetString(res.LID)
+		case fieldAttr:
+			raw, ok := res.Attributes[f.name]
+			if !ok {
+				continue
+			}
+
+			if err := json.Unmarshal(raw, fv.Addr().Interface()); err != nil {
+				return fmt.Errorf("%w: %s/attributes/%s: %s", ErrInvalidDocument, pointer, f.name, err)
+			}
+		case fieldRelation:
+			rel, ok := res.Relationships[f.name]
+			if !ok || rel == nil || rel.Data == nil {
+				continue
+			}
+
+			if err := u.relationship(rel.Data, fv, pointer+"/relationships/"+f.name+"/data"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (u unmarshaler) relationship(data *Data, fv reflect.Value, pointer string) error {
+	if fv.Kind() != reflect.Slice {
+		if data.IsMany {
+			return fmt.Errorf("%w: %s is not a resource identifier", ErrInvalidDocument, pointer)
+		}
+
+		return u.related(data.One, fv, pointer)
+	}
+
+	if !data.IsMany {
+		return fmt.Errorf("%w: %s is not an array", ErrInvalidDocument, pointer)
+	}
+
+	slice := reflect.MakeSlice(fv.Type(), len(data.Many), len(data.Many))
+
+	for i, id := range data.Many {
+		if err := u.related(id, slice.Index(i), fmt.Sprintf("%s/%d", pointer, i)); err != nil {
+			return err
+		}
+	}
+
+	fv.Set(slice)
+
+	return nil
+}
+
+// related decodes the related resource from the included one if present,
+// otherwise from its identifier.
+func (u unmarshaler) related(id *Resource, v reflect.Value, pointer string) error {
+	if id == nil || v.Kind() != reflect.Ptr {
+		return u.value(id, v, pointer)
+	}
+
+	key := resourceKey(id)
+
+	if existing, ok := u.values[key]; ok && existing.Type() == v.Type() {
+		v.Set(existing)
+		return nil
+	}
+
+	res := id
+	if included, ok := u.included[key]; ok {
+		res = included
+	}
+
+	ptr := reflect.New(v.Type().Elem())
+	u.values[key] = ptr
+
+	if err := u.resource(res, ptr.Elem(), pointer); err != nil {
+		return err
+	}
+
+	v.Set(ptr)
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testArticle struct {
+	ID       string         `jsonapi:"primary,articles"`
+	Title    string         `jsonapi:"attr,title"`
+	Summary  string         `jsonapi:"attr,summary,omitempty"`
+	Author   *testPerson    `jsonapi:"relation,author"`
+	Comments []*testComment `jsonapi:"relation,comments,omitempty"`
+}
+
+func (a testArticle) ResourceLinks() Links {
+	return Links{"self": {Href: "/articles/" + a.ID}}
+}
+
+func (a testArticle) RelationshipLinks(name string) Links {
+	return Links{"related": {Href: "/articles/" + a.ID + "/" + name}}
+}
+
+type testPerson struct {
+	ID   int    `jsonapi:"primary,people"`
+	Name string `jsonapi:"attr,name,omitempty"`
+}
+
+type testComment struct {
+	ID     uint        `jsonapi:"primary,comments"`
+	LID    string      `jsonapi:"lid"`
+	Body   string      `jsonapi:"attr,body"`
+	Author *testPerson `jsonapi:"relation,author,omitempty"`
+}
+
+func TestMarshal(t *testing.T) {
+	author := &testPerson{ID: 9, Name: "Dan"}
+
+	tests := map[string]struct {
+		give interface{}
+		want string
+	}{
+		"single resource": {
+			&testArticle{ID: "1", Title: "JSON:API", Author: author},
+			`{
+				"data": {
+					"type": "articles", "id": "1",
+					"attributes": {"title": "JSON:API"},
+					"relationships": {
+						"author": {"data": {"type": "people", "id": "9"}, "links": {"related": "/articles/1/author"}}
+					},
+					"links": {"self": "/articles/1"}
+				},
+				"included": [{"type": "people", "id": "9", "attributes": {"name": "Dan"}}],
+				"jsonapi": {"version": "1.1"}
+			}`,
+		},
+		"identifier only relation": {
+			testArticle{ID: "1", Author: &testPerson{ID: 9}},
+			`{
+				"data": {
+					"type": "articles", "id": "1",
+					"attributes": {"title": ""},
+					"relationships": {
+						"author": {"data": {"type": "people", "id": "9"}, "links": {"related": "/articles/1/author"}}
+					},
+					"links": {"self": "/articles/1"}
+				},
+				"jsonapi": {"version": "1.1"}
+			}`,
+		},
+		"new resource": {
+			&testComment{LID: "new", Body: "First"},
+			`{
+				"data": {"type": "comments", "lid": "new", "attributes": {"body": "First"}},
+				"jsonapi": {"version": "1.1"}
+			}`,
+		},
+		"collection": {
+			[]*testComment{
+				{ID: 1, Body: "First", Author: author},
+				{ID: 2, Body: "Second", Author: author},
+			},
+			`{
+				"data": [
+					{"type": "comments", "id": "1", "attributes": {"body": "First"}, "relationships": {"author": {"data": {"type": "people", "id": "9"}}}},
+					{"type": "comments", "id": "2", "attributes": {"body": "Second"}, "relationships": {"author": {"data": {"type": "people", "id": "9"}}}}
+				],
+				"included": [{"type": "people", "id": "9", "attributes": {"name": "Dan"}}],
+				"jsonapi": {"version": "1.1"}
+			}`,
+		},
+		"empty collection": {
+			[]testComment{},
+			`{"data": [], "jsonapi": {"version": "1.1"}}`,
+		},
+		"nested includes": {
+			&testArticle{ID: "1", Title: "JSON:API", Comments: []*testComment{{ID: 5, Body: "Nice", Author: author}}},
+			`{
+				"data": {
+					"type": "articles", "id": "1",
+					"attributes": {"title": "JSON:API"},
+					"relationships": {
+						"author": {"data": null, "links": {"related": "/articles/1/author"}},
+						"comments": {"data": [{"type": "comments", "id": "5"}], "links": {"related": "/articles/1/comments"}}
+					},
+					"links": {"self": "/articles/1"}
+				},
+				"included": [
+					{"type": "comments", "id": "5", "attributes": {"body": "Nice"}, "relationships": {"author": {"data": {"type": "people", "id": "9"}}}},
+					{"type": "people", "id": "9", "attributes": {"name": "Dan"}}
+				],
+				"jsonapi": {"version": "1.1"}
+			}`,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			doc, err := Marshal(tc.give)
+			require.NoError(t, err)
+
+			b, err := json.Marshal(doc)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tc.want, string(b))
+		})
+	}
+}
+
+func TestMarshal_Invalid(t *testing.T) {
+	type noPrimary struct {
+		Name string `jsonapi:"attr,name"`
+	}
+
+	type badTag struct {
+		ID string `jsonapi:"primary"`
+	}
+
+	_, err := Marshal(&noPrimary{})
+	assert.EqualError(t, err, "jsonapi: no primary field in jsonapi.noPrimary")
+
+	_, err = Marshal(&badTag{})
+	assert.EqualError(t, err, `jsonapi: invalid tag "primary" of jsonapi.badTag.ID`)
+
+	_, err = Marshal("articles")
+	assert.EqualError(t, err, "jsonapi: cannot marshal string")
+
+	type unexported struct {
+		ID    string `jsonapi:"primary,articles"`
+		title string `jsonapi:"attr,title"`
+	}
+
+	_, err = Marshal(&unexported{title: "a"})
+	assert.EqualError(t, err, "jsonapi: unexported field jsonapi.unexported.title")
+
+	type badRelation struct {
+		ID     string     `jsonapi:"primary,articles"`
+		Author testPerson `jsonapi:"relation,author"`
+	}
+
+	_, err = Marshal(&badRelation{})
+	assert.EqualError(t, err, "jsonapi: jsonapi.badRelation.Author must be a pointer, slice or interface, not struct")
+
+	type badLID struct {
+		ID  string `jsonapi:"primary,articles"`
+		LID int    `jsonapi:"lid"`
+	}
+
+	_, err = Marshal(&badLID{})
+	assert.EqualError(t, err, "jsonapi: jsonapi.badLID.LID must be a string, not int")
+}
+
+func TestMarshal_InterfaceRelation(t *testing.T) {
+	type post struct {
+		ID     string      `jsonapi:"primary,posts"`
+		Author interface{} `jsonapi:"relation,author"`
+		Editor interface{} `jsonapi:"relation,editor,omitempty"`
+	}
+
+	doc, err := Marshal(&post{ID: "1", Author: &testPerson{ID: 9}})
+	require.NoError(t, err)
+
+	res := doc.Data.One
+	require.Contains(t, res.Relationships, "author")
+	assert.Equal(t, &Resource{Type: "people", ID: "9"}, res.Relationships["author"].Data.One)
+	assert.NotContains(t, res.Relationships, "editor")
+}
+
+func TestUnmarshal(t *testing.T) {
+	payload := `{
+		"data": {
+			"type": "articles", "id": "1",
+			"attributes": {"title": "JSON:API", "unknown": true},
+			"relationships": {
+				"author": {"data": {"type": "people", "id": "9"}},
+				"comments": {"data": [{"type": "comments", "id": "5"}, {"type": "comments", "lid": "new"}]}
+			}
+		},
+		"included": [
+			{"type": "comments", "id": "5", "attributes": {"body": "Nice"}, "relationships": {"author": {"data": {"type": "people", "id": "9"}}}},
+			{"type": "people", "id": "9", "attributes": {"name": "Dan"}}
+		]
+	}`
+
+	var doc Document
+	require.NoError(t, json.Unmarshal([]byte(payload), &doc))
+
+	var got testArticle
+	require.NoError(t, Unmarshal(&doc, &got))
+
+	author := &testPerson{ID: 9, Name: "Dan"}
+
+	assert.Equal(t, testArticle{
+		ID:     "1",
+		Title:  "JSON:API",
+		Author: author,
+		Comments: []*testComment{
+			{ID: 5, Body: "Nice", Author: author},
+			{LID: "new"},
+		},
+	}, got)
+	assert.Same(t, got.Author, got.Comments[0].Author, "shared related resource")
+}
+
+func TestUnmarshal_Collection(t *testing.T) {
+	doc := &Document{Data: &Data{IsMany: true, Many: []*Resource{
+		{Type: "people", ID: "1", Attributes: map[string]json.RawMessage{"name": []byte(`"Ann"`)}},
+		{Type: "people", ID: "2"},
+	}}}
+
+	var got []testPerson
+	require.NoError(t, Unmarshal(doc, &got))
+
+	assert.Equal(t, []testPerson{{ID: 1, Name: "Ann"}, {ID: 2}}, got)
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	tests := map[string]struct {
+		give       string
+		wantErr    string
+		wantStatus int
+	}{
+		"missing data": {
+			`{"meta": {}}`,
+			"invalid document: missing data",
+			400,
+		},
+		"array data": {
+			`{"data": []}`,
+			"invalid document: data is not a resource object",
+			400,
+		},
+		"type conflict": {
+			`{"data": {"type": "people", "id": "1"}}`,
+			`resource type conflict: /data/type is "people", not "articles"`,
+			409,
+		},
+		"related type conflict": {
+			`{"data": {"type": "articles", "relationships": {"author": {"data": {"type": "articles", "id": "1"}}}}}`,
+			`resource type conflict: /data/relationships/author/data/type is "articles", not "people"`,
+			409,
+		},
+		"invalid attribute": {
+			`{"data": {"type": "articles", "attributes": {"title": 1}}}`,
+			"invalid document: /data/attributes/title: json: cannot unmarshal number into Go value of type string",
+			400,
+		},
+		"invalid id": {
+			`{"data": {"type": "articles", "relationships": {"author": {"data": {"type": "people", "id": "x"}}}}}`,
+			`invalid document: invalid id "x"`,
+			400,
+		},
+		"to-many linkage": {
+			`{"data": {"type": "articles", "relationships": {"comments": {"data": {"type": "comments", "id": "1"}}}}}`,
+			"invalid document: /data/relationships/comments/data is not an array",
+			400,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var doc Document
+			require.NoError(t, json.Unmarshal([]byte(tc.give), &doc))
+
+			err := Unmarshal(&doc, &testArticle{})
+
+			assert.EqualError(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantStatus, StatusCode(err))
+		})
+	}
+}
+
+func TestLink_JSON(t *testing.T) {
+	tests := map[string]struct {
+		give Link
+		want string
+	}{
+		"href only": {
+			Link{Href: "/articles/1"},
+			`"/articles/1"`,
+		},
+		"link object": {
+			Link{Href: "/articles/1", Title: "Article", Meta: Meta{"count": float64(1)}},
+			`{"href": "/articles/1", "title": "Article", "meta": {"count": 1}}`,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := json.Marshal(tc.give)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(b))
+
+			var got Link
+			require.NoError(t, json.Unmarshal(b, &got))
+			assert.Equal(t, tc.give, got)
+		})
+	}
+}
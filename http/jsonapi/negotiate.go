@@ -0,0 +1,128 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MediaType is the JSON:API media type.
+const MediaType = "application/vnd.api+json"
+
+// Negotiate is a middleware that enforces the JSON:API content negotiation.
+// It responds with 415 Unsupported Media Type if the request body is not of
+// MediaType or has parameters other than "profile", and with 406 Not
+// Acceptable if every MediaType in the Accept header has such parameters.
+// Extensions are not supported, so the "ext" parameter is rejected as well.
+func Negotiate(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		if r.ContentLength != 0 {
+			mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != MediaType || !supportedParams(params) {
+				WriteErrors(w, http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+
+		if !acceptable(r.Header.Values("Accept")) {
+			WriteErrors(w, http.StatusNotAcceptable)
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// acceptable reports whether the Accept header has no MediaType, or at least
+// one of them without the unsupported parameters.
+func acceptable(accept []string) bool {
+	var found bool
+
+	for _, value := range accept {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil || mediaType != MediaType {
+				continue
+			}
+
+			found = true
+
+			// The quality value is not a media type parameter, but it is
+			// parsed as one.
+			delete(params, "q")
+
+			if supportedParams(params) {
+				return true
+			}
+		}
+	}
+
+	return !found
+}
+
+func supportedParams(params map[string]string) bool {
+	for name := range params {
+		if name != "profile" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WriteDocument writes the document to response with the given status code
+// and MediaType content type.
+func WriteDocument(w http.ResponseWriter, status int, doc *Document) {
+	content, err := json.Marshal(doc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeContent(w, status, MediaType, content)
+}
+
+// WriteResource writes the document with v as the primary data, marshaled
+// with Marshal, to response with the given status code.
+func WriteResource(w http.ResponseWriter, status int, v interface{}) {
+	doc, err := Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	WriteDocument(w, status, doc)
+}
+
+// WriteErrors writes the errors document with the given status code. An
+// error with the status text is written if no errors are given.
+func WriteErrors(w http.ResponseWriter, status int, errs ...ErrorObject) {
+	if len(errs) == 0 {
+		errs = []ErrorObject{{
+			Status: strconv.Itoa(status),
+			Title:  http.StatusText(status),
+		}}
+	}
+
+	WriteDocument(w, status, &Document{
+		Errors:  errs,
+		JSONAPI: &Object{Version: Version},
+	})
+}
+
+// ReadResource reads the document from the request body and unmarshals its
+// primary data into v with Unmarshal. The error status code is returned by
+// StatusCode.
+func ReadResource(r *http.Request, v interface{}, opts ...ReadOption) error {
+	var doc Document
+
+	if err := Read(r, &doc, opts...); err != nil {
+		return err
+	}
+
+	return Unmarshal(&doc, v)
+}
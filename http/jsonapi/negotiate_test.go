@@ -0,0 +1,138 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]struct {
+		giveBody        string
+		giveContentType string
+		giveAccept      []string
+		wantCode        int
+	}{
+		"no body": {
+			wantCode: http.StatusOK,
+		},
+		"media type": {
+			giveBody:        "{}",
+			giveContentType: MediaType,
+			giveAccept:      []string{MediaType},
+			wantCode:        http.StatusOK,
+		},
+		"profile parameter": {
+			giveBody:        "{}",
+			giveContentType: MediaType + `; profile="https://example.com/timestamps"`,
+			wantCode:        http.StatusOK,
+		},
+		"plain json": {
+			giveBody:        "{}",
+			giveContentType: "application/json",
+			wantCode:        http.StatusUnsupportedMediaType,
+		},
+		"unsupported parameter": {
+			giveBody:        "{}",
+			giveContentType: MediaType + "; charset=utf-8",
+			wantCode:        http.StatusUnsupportedMediaType,
+		},
+		"unsupported extension": {
+			giveBody:        "{}",
+			giveContentType: MediaType + `; ext="https://jsonapi.org/ext/atomic"`,
+			wantCode:        http.StatusUnsupportedMediaType,
+		},
+		"accept other types": {
+			giveAccept: []string{"application/json, */*;q=0.8"},
+			wantCode:   http.StatusOK,
+		},
+		"accept with quality": {
+			giveAccept: []string{MediaType + ";q=0.9"},
+			wantCode:   http.StatusOK,
+		},
+		"accept one supported instance": {
+			giveAccept: []string{MediaType + "; charset=utf-8", MediaType},
+			wantCode:   http.StatusOK,
+		},
+		"accept only unsupported instances": {
+			giveAccept: []string{MediaType + "; charset=utf-8, " + MediaType + `; ext="https://jsonapi.org/ext/atomic"`},
+			wantCode:   http.StatusNotAcceptable,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(tc.giveBody))
+			r.Header.Set("Content-Type", tc.giveContentType)
+
+			for _, accept := range tc.giveAccept {
+				r.Header.Add("Accept", accept)
+			}
+
+			rec := httptest.NewRecorder()
+
+			Negotiate(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})(rec, r)
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+
+			if tc.wantCode != http.StatusOK {
+				assert.Equal(t, MediaType, rec.Header().Get("Content-Type"))
+				assert.Contains(t, rec.Body.String(), `"status":"`+strconv.Itoa(tc.wantCode)+`"`)
+			}
+		})
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteErrors(rec, http.StatusNotFound)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, MediaType, rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"errors": [{"status": "404", "title": "Not Found"}], "jsonapi": {"version": "1.1"}}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	WriteErrors(rec, http.StatusUnprocessableEntity, ErrorObject{
+		Status: "422",
+		Title:  "Invalid Attribute",
+		Source: &ErrorSource{Pointer: "/data/attributes/title"},
+	})
+
+	assert.JSONEq(t, `{
+		"errors": [{"status": "422", "title": "Invalid Attribute", "source": {"pointer": "/data/attributes/title"}}],
+		"jsonapi": {"version": "1.1"}
+	}`, rec.Body.String())
+}
+
+func TestReadResource_WriteResource(t *testing.T) {
+	body := `{"data": {"type": "comments", "lid": "new", "attributes": {"body": "First"}}}`
+	r := httptest.NewRequest(http.MethodPost, "/comments", strings.NewReader(body))
+
+	var comment testComment
+	require.NoError(t, ReadResource(r, &comment, WithMaxBytes(1024)))
+
+	assert.Equal(t, testComment{LID: "new", Body: "First"}, comment)
+
+	comment.ID = 1
+	rec := httptest.NewRecorder()
+	WriteResource(rec, http.StatusCreated, &comment)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, MediaType, rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"data": {"type": "comments", "id": "1", "lid": "new", "attributes": {"body": "First"}},
+		"jsonapi": {"version": "1.1"}
+	}`, rec.Body.String())
+}
@@ -0,0 +1,331 @@
+// Package jwt provides verification of JSON Web Tokens in incoming requests,
+// with HS256, RS256 and ES256 signatures, keys loaded from a JWKS endpoint,
+// registered claims validation and per-route scope checks.
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Verification errors.
+var (
+	ErrMalformed            = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrInvalidKey           = errors.New("invalid key for algorithm")
+	ErrUnknownKey           = errors.New("unknown key")
+	ErrInvalidSignature     = errors.New("invalid signature")
+	ErrExpired              = errors.New("token is expired")
+	ErrMissingExpiration    = errors.New("token has no expiration")
+	ErrNotValidYet          = errors.New("token is not valid yet")
+	ErrInvalidIssuer        = errors.New("invalid issuer")
+	ErrInvalidAudience      = errors.New("invalid audience")
+)
+
+// Claims are the verified token claims.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+	// Scopes are collected from the space-delimited "scope" claim and the
+	// "scp" claim.
+	Scopes []string
+	// Raw contains all the claims of the token, including the custom ones.
+	Raw map[string]interface{}
+}
+
+// HasScopes reports whether the claims contain all the given scopes.
+func (c *Claims) HasScopes(scopes ...string) bool {
+	for _, s := range scopes {
+		found := false
+
+		for _, cs := range c.Scopes {
+			if cs == s {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// KeySet provides verification keys: []byte for HS256, *rsa.PublicKey for
+// RS256 and *ecdsa.PublicKey for ES256.
+type KeySet interface {
+	// Key returns the key with the given key ID for the algorithm. The key ID
+	// is empty if the token header does not have it.
+	Key(ctx context.Context, kid, alg string) (interface{}, error)
+}
+
+// Config is a configuration for the token verification.
+type Config struct {
+	// Keys is the source of verification keys.
+	Keys KeySet
+	// Issuer is the expected "iss" claim, not checked if empty.
+	Issuer string
+	// Audience is the expected value in "aud" claim, not checked if empty.
+	Audience string
+	// Algorithms is a list of accepted algorithms, defaults to all supported.
+	Algorithms []string
+	// Leeway is the allowed clock skew for "exp" and "nbf" checks.
+	Leeway time.Duration
+	// AllowMissingExpiration accepts tokens without "exp" claim, which never
+	// expire. They are rejected by default.
+	AllowMissingExpiration bool
+}
+
+// Verifier verifies tokens and their claims.
+type Verifier struct {
+	cfg Config
+	now func() time.Time
+}
+
+// NewVerifier returns a new instance of Verifier.
+func NewVerifier(cfg Config) *Verifier {
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = []string{HS256, RS256, ES256}
+	}
+
+	return &Verifier{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type registeredClaims struct {
+	Issuer    string     `json:"iss"`
+	Subject   string     `json:"sub"`
+	Audience  stringList `json:"aud"`
+	ExpiresAt float64    `json:"exp"`
+	NotBefore float64    `json:"nbf"`
+	IssuedAt  float64    `json:"iat"`
+	ID        string     `json:"jti"`
+	Scope     stringList `json:"scope"`
+	Scp       stringList `json:"scp"`
+}
+
+// Verify checks the token signature and claims, and returns the claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+
+	if !v.accepts(h.Alg) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, h.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	key, err := v.cfg.Keys.Key(ctx, h.Kid, h.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(h.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var rc registeredClaims
+	if err := decodeSegment(parts[1], &rc); err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{
+		Issuer:    rc.Issuer,
+		Subject:   rc.Subject,
+		Audience:  rc.Audience,
+		ExpiresAt: numericDate(rc.ExpiresAt),
+		NotBefore: numericDate(rc.NotBefore),
+		IssuedAt:  numericDate(rc.IssuedAt),
+		ID:        rc.ID,
+	}
+
+	for _, s := range append(rc.Scope, rc.Scp...) {
+		claims.Scopes = append(claims.Scopes, strings.Fields(s)...)
+	}
+
+	if err := decodeSegment(parts[1], &claims.Raw); err != nil {
+		return nil, err
+	}
+
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) accepts(alg string) bool {
+	for _, a := range v.cfg.Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *Verifier) validate(c *Claims) error {
+	now := v.now()
+
+	switch {
+	case c.ExpiresAt.IsZero() && !v.cfg.AllowMissingExpiration:
+		return ErrMissingExpiration
+	case !c.ExpiresAt.IsZero() && now.After(c.ExpiresAt.Add(v.cfg.Leeway)):
+		return ErrExpired
+	}
+
+	if !c.NotBefore.IsZero() && now.Add(v.cfg.Leeway).Before(c.NotBefore) {
+		return ErrNotValidYet
+	}
+
+	if v.cfg.Issuer != "" && c.Issuer != v.cfg.Issuer {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, c.Issuer)
+	}
+
+	if v.cfg.Audience != "" {
+		for _, a := range c.Audience {
+			if a == v.cfg.Audience {
+				return nil
+			}
+		}
+
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+func verifySignature(alg string, key interface{}, signed, sig []byte) error {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case HS256:
+		k, ok := key.([]byte)
+		if !ok {
+			return ErrInvalidKey
+		}
+
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed)
+
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+	case RS256:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidKey
+		}
+
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			return ErrInvalidSignature
+		}
+	case ES256:
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || k.Curve != elliptic.P256() {
+			return ErrInvalidKey
+		}
+
+		if len(sig) != 64 {
+			return ErrInvalidSignature
+		}
+
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	return nil
+}
+
+func numericDate(f float64) time.Time {
+	if f == 0 {
+		return time.Time{}
+	}
+
+	sec, frac := math.Modf(f)
+
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// stringList is a claim that is either a single string or an array.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*l = stringList{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+
+	*l = list
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func sign(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+
+	h, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	secret := []byte("secret")
+	keys := StaticKeys{
+		"hs": secret,
+		"rs": &rsaKey.PublicKey,
+		"es": &ecKey.PublicKey,
+	}
+
+	valid := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":   "https://issuer",
+			"sub":   "user",
+			"aud":   []string{"api", "other"},
+			"exp":   testNow.Add(time.Minute).Unix(),
+			"nbf":   testNow.Add(-time.Minute).Unix(),
+			"scope": "read write",
+		}
+
+		for k, v := range extra {
+			c[k] = v
+		}
+
+		return c
+	}
+
+	tests := map[string]struct {
+		giveToken string
+		wantErr   error
+	}{
+		"hs256": {
+			sign(t, HS256, "hs", secret, valid(nil)),
+			nil,
+		},
+		"rs256": {
+			sign(t, RS256, "rs", rsaKey, valid(nil)),
+			nil,
+		},
+		"es256": {
+			sign(t, ES256, "es", ecKey, valid(nil)),
+			nil,
+		},
+		"wrong secret": {
+			sign(t, HS256, "hs", []byte("other"), valid(nil)),
+			ErrInvalidSignature,
+		},
+		"algorithm confusion": {
+			sign(t, HS256, "rs", secret, valid(nil)),
+			ErrInvalidKey,
+		},
+		"none algorithm": {
+			sign(t, "none", "hs", secret, valid(nil)),
+			ErrUnsupportedAlgorithm,
+		},
+		"unknown key": {
+			sign(t, HS256, "unknown", secret, valid(nil)),
+			ErrUnknownKey,
+		},
+		"malformed": {
+			"abc.def",
+			ErrMalformed,
+		},
+		"expired": {
+			sign(t, HS256, "hs", secret, valid(map[string]interface{}{"exp": testNow.Add(-time.Minute).Unix()})),
+			ErrExpired,
+		},
+		"expired within leeway": {
+			sign(t, HS256, "hs", secret, valid(map[string]interface{}{"exp": testNow.Add(-20 * time.Second).Unix()})),
+			nil,
+		},
+		"missing expiration": {
+			sign(t, HS256, "hs", secret, valid(map[string]interface{}{"exp": nil})),
+			ErrMissingExpiration,
+		},
+		"not valid yet": {
+			sign(t, HS256, "hs", secret, valid(map[string]interface{}{"nbf": testNow.Add(time.Minute).Unix()})),
+			ErrNotValidYet,
+		},
+		"invalid issuer": {
+			sign(t, HS256, "hs", secret, valid(map[string]interface{}{"iss": "https://other"})),
+			ErrInvalidIssuer,
+		},
+		"invalid audience": {
+			sign(t, HS256, "hs", secret, valid(map[string]interface{}{"aud": "other"})),
+			ErrInvalidAudience,
+		},
+	}
+
+	v := NewVerifier(Config{
+		Keys:     keys,
+		Issuer:   "https://issuer",
+		Audience: "api",
+		Leeway:   30 * time.Second,
+	})
+	v.now = func() time.Time { return testNow }
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			claims, err := v.Verify(context.Background(), tc.giveToken)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "user", claims.Subject)
+			assert.Equal(t, []string{"api", "other"}, claims.Audience)
+			assert.Equal(t, []string{"read", "write"}, claims.Scopes)
+			assert.Equal(t, "user", claims.Raw["sub"])
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	key1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		rotated int32
+		fetches int32
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+
+		keys := []map[string]string{{
+			"kty": "EC", "kid": "k1", "crv": "P-256", "use": "sig",
+			"x": base64.RawURLEncoding.EncodeToString(key1.X.Bytes()),
+			"y": base64.RawURLEncoding.EncodeToString(key1.Y.Bytes()),
+		}}
+
+		if atomic.LoadInt32(&rotated) == 1 {
+			keys = append(keys, map[string]string{
+				"kty": "RSA", "kid": "k2", "alg": RS256,
+				"n": base64.RawURLEncoding.EncodeToString(key2.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key2.E)).Bytes()),
+			})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer srv.Close()
+
+	now := testNow
+	jwks := NewJWKS(JWKSConfig{URL: srv.URL, Client: srv.Client()})
+	jwks.now = func() time.Time { return now }
+
+	v := NewVerifier(Config{Keys: jwks})
+	v.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	claims := map[string]interface{}{"sub": "user", "exp": testNow.Add(3 * time.Hour).Unix()}
+
+	_, err = v.Verify(ctx, sign(t, ES256, "k1", key1, claims))
+	require.NoError(t, err)
+
+	_, err = v.Verify(ctx, sign(t, ES256, "k1", key1, claims))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "keys are cached")
+
+	atomic.StoreInt32(&rotated, 1)
+
+	_, err = v.Verify(ctx, sign(t, RS256, "k2", key2, claims))
+	assert.ErrorIs(t, err, ErrUnknownKey, "refetch is rate limited")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	now = now.Add(2 * time.Minute)
+
+	_, err = v.Verify(ctx, sign(t, RS256, "k2", key2, claims))
+	require.NoError(t, err, "rotated key is fetched")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	now = now.Add(2 * time.Hour)
+
+	_, err = v.Verify(ctx, sign(t, ES256, "k1", key1, claims))
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fetches), "expired keys are refreshed")
+
+	_, err = jwks.Key(ctx, "k1", RS256)
+	assert.ErrorIs(t, err, ErrUnknownKey, "key type must match the algorithm")
+}
+
+func TestJWKS_AbortedRequest(t *testing.T) {
+	var fetches int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, `{"keys":[{"kty":"oct","kid":"hs","k":"c2VjcmV0"}]}`)
+	}))
+	defer srv.Close()
+
+	jwks := NewJWKS(JWKSConfig{URL: srv.URL, Client: srv.Client()})
+	jwks.now = func() time.Time { return testNow }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := jwks.Key(ctx, "hs", HS256)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	key, err := jwks.Key(context.Background(), "hs", HS256)
+	require.NoError(t, err, "fetch continues after the request is aborted")
+	assert.Equal(t, []byte("secret"), key)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestVerifier_AllowMissingExpiration(t *testing.T) {
+	secret := []byte("secret")
+	v := NewVerifier(Config{Keys: StaticKeys{"": secret}, AllowMissingExpiration: true})
+	v.now = func() time.Time { return testNow }
+
+	claims, err := v.Verify(context.Background(), sign(t, HS256, "", secret, map[string]interface{}{"sub": "user"}))
+	require.NoError(t, err)
+	assert.True(t, claims.ExpiresAt.IsZero())
+}
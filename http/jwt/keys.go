@@ -0,0 +1,314 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// StaticKeys is a KeySet of fixed keys by key ID. The only key is used for
+// tokens without the key ID.
+type StaticKeys map[string]interface{}
+
+// Key returns the key with the given key ID.
+func (k StaticKeys) Key(_ context.Context, kid, _ string) (interface{}, error) {
+	if key, ok := k[kid]; ok {
+		return key, nil
+	}
+
+	if kid == "" && len(k) == 1 {
+		for _, key := range k {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+const (
+	defaultJWKSRefresh    = time.Hour
+	defaultJWKSMinRefresh = time.Minute
+	jwksFetchTimeout      = 10 * time.Second
+)
+
+// JWKSConfig is a configuration for the JWKS key set.
+type JWKSConfig struct {
+	// URL is the JWKS endpoint.
+	URL string
+	// Client is the HTTP client, defaults to http.DefaultClient.
+	Client *http.Client
+	// RefreshInterval is the maximum age of the cached keys, defaults to an
+	// hour.
+	RefreshInterval time.Duration
+	// MinRefreshInterval is the minimum time between fetches triggered by an
+	// unknown key ID, defaults to a minute.
+	MinRefreshInterval time.Duration
+}
+
+// JWKS is a KeySet fetched from a JSON Web Key Set endpoint. The keys are
+// cached and refetched when they get older than the refresh interval, or when
+// a token is signed with an unknown key ID, to pick up the rotated keys. The
+// cached keys are kept if the refresh fails. Concurrent refreshes share a
+// single fetch, which does not block the lookups of cached keys.
+type JWKS struct {
+	cfg     JWKSConfig
+	keys    []jwk
+	fetched time.Time
+	group   singleflight.Group
+	mu      sync.RWMutex
+	now     func() time.Time
+}
+
+type jwk struct {
+	kid string
+	kty string
+	alg string
+	key interface{}
+}
+
+// NewJWKS returns a new instance of JWKS.
+func NewJWKS(cfg JWKSConfig) *JWKS {
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	if cfg.RefreshInterval == 0 {
+		cfg.RefreshInterval = defaultJWKSRefresh
+	}
+
+	if cfg.MinRefreshInterval == 0 {
+		cfg.MinRefreshInterval = defaultJWKSMinRefresh
+	}
+
+	return &JWKS{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Key returns the key with the given key ID for the algorithm.
+func (s *JWKS) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	keys, fetched := s.cached()
+	now := s.now()
+
+	if fetched.IsZero() || now.Sub(fetched) > s.cfg.RefreshInterval {
+		if err := s.refresh(ctx); err != nil && keys == nil {
+			return nil, err
+		}
+
+		keys, fetched = s.cached()
+	}
+
+	if key := find(keys, kid, alg); key != nil {
+		return key, nil
+	}
+
+	if now.Sub(fetched) >= s.cfg.MinRefreshInterval {
+		if err := s.refresh(ctx); err != nil {
+			return nil, err
+		}
+
+		keys, _ = s.cached()
+
+		if key := find(keys, kid, alg); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+func (s *JWKS) cached() ([]jwk, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.keys, s.fetched
+}
+
+// find returns the key with the given key ID, of the type used by the
+// algorithm family, as the key algorithm is optional.
+func find(keys []jwk, kid, alg string) interface{} {
+	kty := keyTypes[alg]
+
+	for _, k := range keys {
+		if (kid == "" || k.kid == kid) && k.kty == kty && (k.alg == "" || k.alg == alg) {
+			return k.key
+		}
+	}
+
+	return nil
+}
+
+// keyTypes maps the supported algorithms to their JWK key types.
+var keyTypes = map[string]string{
+	HS256: "oct",
+	RS256: "RSA",
+	ES256: "EC",
+}
+
+// refresh fetches the keys, sharing the fetch in progress. It returns early if
+// the context is done, while the fetch continues, as it is not bound to the
+// context of a single request.
+func (s *JWKS) refresh(ctx context.Context) error {
+	ch := s.group.DoChan("", func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+		defer cancel()
+
+		return nil, s.fetch(ctx)
+	})
+
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *JWKS) fetch(ctx context.Context) error {
+	keys, err := s.get(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The fetch time is updated on failures too, so that the endpoint is not
+	// hammered with requests.
+	s.fetched = s.now()
+
+	if err != nil {
+		return err
+	}
+
+	s.keys = keys
+
+	return nil
+}
+
+func (s *JWKS) get(ctx context.Context) ([]jwk, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make([]jwk, 0, len(set.Keys))
+
+	for _, raw := range set.Keys {
+		k, err := parseJWK(raw)
+		if err != nil {
+			// Keys of unsupported types and uses are skipped.
+			continue
+		}
+
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+var errUnsupportedJWK = errors.New("unsupported jwk")
+
+func parseJWK(raw json.RawMessage) (jwk, error) {
+	var k struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+		K   string `json:"k"`
+	}
+
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return jwk{}, err
+	}
+
+	if k.Use != "" && k.Use != "sig" {
+		return jwk{}, errUnsupportedJWK
+	}
+
+	res := jwk{kid: k.Kid, kty: k.Kty, alg: k.Alg}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return jwk{}, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return jwk{}, err
+		}
+
+		res.key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		if k.Crv != "P-256" {
+			return jwk{}, errUnsupportedJWK
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return jwk{}, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return jwk{}, err
+		}
+
+		res.key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	case "oct":
+		b, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return jwk{}, err
+		}
+
+		res.key = b
+	default:
+		return jwk{}, errUnsupportedJWK
+	}
+
+	return res, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	httpkit "github.com/diptanw/go-toolkit/http"
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+type contextKey int
+
+const claimsKey contextKey = iota
+
+// Middleware returns a middleware func that verifies the bearer token in the
+// Authorization header and puts the claims into the request context. Requests
+// without a valid token are responded with 401 Unauthorized, the reason is
+// logged with the Info level and not disclosed to the client.
+func Middleware(v *Verifier, log logger.Logger) httpkit.MiddlewareFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				jsonapi.WriteError(w, http.StatusUnauthorized)
+
+				return
+			}
+
+			claims, err := v.Verify(r.Context(), token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				jsonapi.WriteError(w, http.StatusUnauthorized, "invalid token")
+				log.WithContext(r.Context()).With("error", err).Infof("invalid token")
+
+				return
+			}
+
+			h.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+		}
+	}
+}
+
+// RequireScopes returns a middleware func that responds with 403 Forbidden if
+// the claims in the request context do not contain all the given scopes. It
+// is meant to wrap route handlers behind Middleware.
+func RequireScopes(scopes ...string) httpkit.MiddlewareFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				jsonapi.WriteError(w, http.StatusUnauthorized)
+
+				return
+			}
+
+			if !claims.HasScopes(scopes...) {
+				w.Header().Set("WWW-Authenticate",
+					`Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+				jsonapi.WriteError(w, http.StatusForbidden, "insufficient scope")
+
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		}
+	}
+}
+
+// ContextWithClaims returns a copy of ctx with the claims.
+func ContextWithClaims(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, c)
+}
+
+// ClaimsFromContext returns the claims from the context.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(claimsKey).(*Claims)
+	return c, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+
+	const prefix = "bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(auth[len(prefix):]), true
+}
@@ -0,0 +1,97 @@
+package jwt
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+func TestMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	v := NewVerifier(Config{Keys: StaticKeys{"": secret}})
+	v.now = func() time.Time { return testNow }
+
+	h := Middleware(v, logger.New(io.Discard, logger.Info))(RequireScopes("write")(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := ClaimsFromContext(r.Context())
+		io.WriteString(w, claims.Subject)
+	}))
+
+	tests := map[string]struct {
+		giveAuth   string
+		wantCode   int
+		wantBody   string
+		wantHeader string
+	}{
+		"valid": {
+			"Bearer " + sign(t, HS256, "", secret, map[string]interface{}{"sub": "user", "exp": testNow.Add(time.Minute).Unix(), "scp": []string{"read", "write"}}),
+			http.StatusOK,
+			"user",
+			"",
+		},
+		"missing token": {
+			"",
+			http.StatusUnauthorized,
+			`{"errors":["Unauthorized"]}`,
+			"Bearer",
+		},
+		"invalid token": {
+			"bearer " + sign(t, HS256, "", []byte("other"), map[string]interface{}{"sub": "user"}),
+			http.StatusUnauthorized,
+			`{"errors":["invalid token"]}`,
+			`Bearer error="invalid_token"`,
+		},
+		"insufficient scope": {
+			"Bearer " + sign(t, HS256, "", secret, map[string]interface{}{"sub": "user", "exp": testNow.Add(time.Minute).Unix(), "scope": "read"}),
+			http.StatusForbidden,
+			`{"errors":["insufficient scope"]}`,
+			`Bearer error="insufficient_scope", scope="write"`,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.giveAuth != "" {
+				req.Header.Set("Authorization", tc.giveAuth)
+			}
+
+			rec := httptest.NewRecorder()
+			h(rec, req)
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+			assert.Equal(t, tc.wantHeader, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestMiddleware_LogsReason(t *testing.T) {
+	v := NewVerifier(Config{Keys: StaticKeys{"": []byte("secret")}})
+	v.now = func() time.Time { return testNow }
+
+	var buf bytes.Buffer
+
+	h := Middleware(v, logger.New(&buf, logger.Info))(func(http.ResponseWriter, *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(t, HS256, "", []byte("secret"), map[string]interface{}{"sub": "user"}))
+
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `{"errors":["invalid token"]}`, rec.Body.String())
+	assert.Contains(t, buf.String(), "invalid token")
+	assert.Contains(t, buf.String(), ErrMissingExpiration.Error())
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/diptanw/go-toolkit/metrics"
+)
+
+// unmatchedRoute is the route reported for requests not matching any route.
+const unmatchedRoute = "unmatched"
+
+// sizeBuckets are the response size histogram buckets in bytes.
+var sizeBuckets = []float64{100, 1000, 10000, 100000, 1e6, 1e7}
+
+// knownMethods are the methods used as metric labels, others are reported as
+// "other" to keep the number of series bounded.
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// Metrics returns a middleware func that registers the HTTP server metrics in
+// the registry and collects them:
+//
+//	http_requests_total             counter   method, route, status
+//	http_requests_in_flight         gauge     method, route
+//	http_request_duration_seconds   histogram method, route, status
+//	http_response_size_bytes        histogram method, route, status
+//
+// The route label is the pattern of the matched Mux route rather than the raw
+// path, or "unmatched" if there is none, and the status label is the status
+// class such as "2xx". Metrics panics if the metrics are already registered.
+func Metrics(reg *metrics.Registry) MiddlewareFunc {
+	var (
+		requests = reg.Counter("http_requests_total",
+			"Total number of HTTP requests.", "method", "route", "status")
+		inFlight = reg.Gauge("http_requests_in_flight",
+			"Number of HTTP requests being served.", "method", "route")
+		duration = reg.Histogram("http_request_duration_seconds",
+			"HTTP request duration in seconds.", metrics.DefBuckets, "method", "route", "status")
+		size = reg.Histogram("http_response_size_bytes",
+			"HTTP response body size in bytes.", sizeBuckets, "method", "route", "status")
+	)
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			method := r.Method
+			if !knownMethods[method] {
+				method = "other"
+			}
+
+			route := RoutePattern(r.Context())
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			gauge := inFlight.With(method, route)
+			gauge.Inc()
+
+			defer gauge.Dec()
+
+			start := time.Now()
+			ww, rec := wrapWriter(w)
+
+			h.ServeHTTP(ww, r)
+
+			status := strconv.Itoa(rec.Status()/100) + "xx"
+
+			requests.With(method, route, status).Inc()
+			duration.With(method, route, status).Observe(time.Since(start).Seconds())
+			size.With(method, route, status).Observe(float64(rec.bytes))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diptanw/go-toolkit/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	mux := &Mux{}
+	mux.WithMiddleware(Metrics(reg))
+	mux.AddRoute(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "user")
+	})
+	mux.AddRoute(http.MethodPost, "/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/users/2", nil),
+		httptest.NewRequest(http.MethodPost, "/users", nil),
+		httptest.NewRequest(http.MethodGet, "/missing", nil),
+		httptest.NewRequest("PROPFIND", "/users/1", nil),
+	} {
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var b strings.Builder
+	require.NoError(t, reg.WriteText(&b))
+
+	out := b.String()
+
+	assert.Contains(t, out, `http_requests_total{method="GET",route="/users/:id",status="2xx"} 2`)
+	assert.Contains(t, out, `http_requests_total{method="POST",route="/users",status="4xx"} 1`)
+	assert.Contains(t, out, `http_requests_total{method="GET",route="unmatched",status="4xx"} 1`)
+	assert.Contains(t, out, `http_requests_total{method="other",route="unmatched",status="4xx"} 1`)
+	assert.Contains(t, out, `http_requests_in_flight{method="GET",route="/users/:id"} 0`)
+	assert.Contains(t, out, `http_request_duration_seconds_count{method="GET",route="/users/:id",status="2xx"} 2`)
+	assert.Contains(t, out, `http_response_size_bytes_bucket{method="GET",route="/users/:id",status="2xx",le="100"} 2`)
+	assert.Contains(t, out, `http_response_size_bytes_sum{method="GET",route="/users/:id",status="2xx"} 8`)
+}
@@ -1,7 +1,9 @@
 package http
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 )
@@ -16,73 +18,93 @@ type Mux struct {
 }
 
 type route struct {
-	method  string
-	regex   *regexp.Regexp
-	params  map[int]string
-	handler http.HandlerFunc
+	method     string
+	pattern    string
+	regex      *regexp.Regexp
+	params     []param
+	host       *regexp.Regexp
+	hostRaw    string
+	hostParams []param
+	headers    []headerMatcher
+	queries    []queryMatcher
+	doc        *RouteDoc
+	handler    http.HandlerFunc
 }
 
+type contextKey int
+
+const (
+	paramsKey contextKey = iota
+	routeKey
+	requestIDKey
+	principalKey
+	cspNonceKey
+)
+
 // WithMiddleware adds a middleware wrapper for the root handler.
 func (m *Mux) WithMiddleware(mw ...MiddlewareFunc) {
 	m.middleware = append(m.middleware, mw...)
 }
 
-// AddRoute adds a new route to the Handler.
-func (m *Mux) AddRoute(method, pattern string, handler http.HandlerFunc) {
-	parts := strings.Split(pattern, "/")
-
-	// find parts that starts with ":" and replace with regex
-	j, params := 0, make(map[int]string)
-
-	for i, part := range parts {
-		if strings.HasPrefix(part, ":") {
-			expr := "([^/]+)"
-			params[j] = part
-			parts[i] = expr
-			j++
-		}
+// AddRoute adds a new route to the Handler. Path parameters are declared as
+// ":name" segments and may be constrained by an inline regular expression
+// ":id{[0-9]+}" or a named type ":id<int>". A trailing "?" marks a parameter
+// segment optional, and a final "*name" segment captures the rest of the path.
+// Options add host, header or query requirements to the route. Routes are
+// matched in the order they were added. AddRoute panics if the pattern or any
+// of the options is invalid.
+func (m *Mux) AddRoute(method, pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	regex, params, err := compilePattern(pattern)
+	if err != nil {
+		panic("http: invalid route pattern " + pattern + ": " + err.Error())
 	}
 
-	pattern = strings.Join(parts, "/")
-	regex := regexp.MustCompile(pattern)
-
-	m.routes = append(m.routes, route{
+	r := route{
 		method:  method,
+		pattern: pattern,
 		regex:   regex,
 		handler: handler,
 		params:  params,
-	})
+	}
+
+	for _, opt := range opts {
+		if err := opt(&r); err != nil {
+			panic("http: invalid route " + pattern + ": " + err.Error())
+		}
+	}
+
+	m.routes = append(m.routes, r)
 }
 
 // ServeHTTP handles all page routing.
 func (m *Mux) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	f := func(rw http.ResponseWriter, r *http.Request) {
-		if ok, route := m.find(r.Method, r.URL.Path); ok {
-			route.handler(rw, r)
-			return
-		}
+	h := http.HandlerFunc(http.NotFound)
 
-		http.NotFound(rw, r)
+	if ok, route := m.find(r, true); ok {
+		h = route.handler
+		ctx := context.WithValue(r.Context(), paramsKey, route.values(r))
+		ctx = context.WithValue(ctx, routeKey, route)
+		r = r.WithContext(ctx)
 	}
 
-	wrap(f, m.middleware)(rw, r)
+	wrap(h, m.middleware)(rw, r)
 }
 
-func (m *Mux) find(method, path string) (bool, route) {
+// find returns the first route matching the request method and path, and its
+// host, header and query requirements if strict is set.
+func (m *Mux) find(r *http.Request, strict bool) (bool, route) {
+	requestPath := normalizePath(r.URL.Path)
+
 	for _, route := range m.routes {
-		if method != route.method {
+		if r.Method != route.method {
 			continue
 		}
 
-		requestPath := strings.TrimPrefix(path, "/")
 		if !route.regex.MatchString(requestPath) {
 			continue
 		}
 
-		matches := route.regex.FindStringSubmatch(requestPath)
-
-		// check that the Route matches the URL pattern.
-		if len(matches[0]) != len(requestPath) {
+		if strict && !route.matchRequest(r) {
 			continue
 		}
 
@@ -92,23 +114,89 @@ func (m *Mux) find(method, path string) (bool, route) {
 	return false, route{}
 }
 
-// GetParams returns a map of params and it's values.
+// GetParams returns a map of params and it's values. Routes are matched on the
+// method and path only, ignoring their host, header and query requirements, so
+// host parameters are not returned.
 func (m *Mux) GetParams(method, path string) map[string]string {
-	if ok, route := m.find(method, path); ok {
+	r := &http.Request{
+		Method: method,
+		URL:    &url.URL{Path: path},
+		Header: http.Header{},
+	}
+
+	if ok, route := m.find(r, false); ok {
 		if len(route.params) > 0 {
-			matches := route.regex.FindStringSubmatch(path)
-			values := make(map[string]string)
+			return route.values(r)
+		}
+	}
 
-			for i, match := range matches[1:] {
-				param := route.params[i][1:]
-				values[param] = match
-			}
+	return nil
+}
 
-			return values
+// Params returns the host and path parameters of the route matched for the
+// request context. Optional parameters that are not present in the path are
+// omitted.
+func Params(ctx context.Context) map[string]string {
+	params, _ := ctx.Value(paramsKey).(map[string]string)
+	return params
+}
+
+// RoutePattern returns the pattern of the route matched for the request
+// context, or an empty string if no route matched.
+func RoutePattern(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey).(route)
+	return route.pattern
+}
+
+// wildcard returns the value of the catch-all parameter of the route matched
+// for the request context.
+func wildcard(ctx context.Context) (string, bool) {
+	route, ok := ctx.Value(routeKey).(route)
+	if !ok {
+		return "", false
+	}
+
+	for _, p := range route.params {
+		if p.wildcard {
+			return Params(ctx)[p.name], true
 		}
 	}
 
-	return nil
+	return "", false
+}
+
+func (r route) values(req *http.Request) map[string]string {
+	values := make(map[string]string, len(r.params)+len(r.hostParams))
+
+	if r.host != nil {
+		collect(values, r.host, r.hostParams, requestHost(req))
+	}
+
+	collect(values, r.regex, r.params, normalizePath(req.URL.Path))
+
+	return values
+}
+
+func collect(values map[string]string, regex *regexp.Regexp, params []param, s string) {
+	matches := regex.FindStringSubmatchIndex(s)
+	if matches == nil {
+		return
+	}
+
+	for i, p := range params {
+		start, end := matches[2*(i+1)], matches[2*(i+1)+1]
+
+		switch {
+		case start >= 0:
+			values[p.name] = s[start:end]
+		case p.wildcard:
+			values[p.name] = ""
+		}
+	}
+}
+
+func normalizePath(path string) string {
+	return "/" + strings.TrimPrefix(path, "/")
 }
 
 func wrap(h http.HandlerFunc, mw []MiddlewareFunc) http.HandlerFunc {
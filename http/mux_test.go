@@ -0,0 +1,333 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMux_AddRoute_invalid(t *testing.T) {
+	tests := map[string]string{
+		"unbalanced braces":       "users/:id{[0-9]+",
+		"capturing group":         "users/:id{([0-9]+)}",
+		"invalid regex":           "users/:id{[0-9}",
+		"unknown type":            "users/:id<float>",
+		"duplicate parameter":     "users/:id/posts/:id",
+		"wildcard not last":       "files/*path/meta",
+		"literal after optional":  "users/:id?/posts",
+		"required after optional": "users/:id?/:name",
+		"invalid name":            "users/:1d",
+	}
+
+	for name, test := range tests {
+		pattern := test
+
+		t.Run(name, func(t *testing.T) {
+			m := Mux{}
+
+			require.Panics(t, func() {
+				m.AddRoute("GET", pattern, func(http.ResponseWriter, *http.Request) {})
+			})
+		})
+	}
+}
+
+func TestMux_ServeHTTP_params(t *testing.T) {
+	tests := map[string]struct {
+		givePattern string
+		givePath    string
+		wantFound   bool
+		wantParams  map[string]string
+	}{
+		"plain parameter": {
+			"/users/:id",
+			"/users/abc",
+			true,
+			map[string]string{"id": "abc"},
+		},
+		"pattern without leading slash": {
+			"users/:id",
+			"/users/abc",
+			true,
+			map[string]string{"id": "abc"},
+		},
+		"regex constraint": {
+			"/users/:id{[0-9]+}",
+			"/users/42",
+			true,
+			map[string]string{"id": "42"},
+		},
+		"regex constraint mismatch": {
+			"/users/:id{[0-9]+}",
+			"/users/abc",
+			false,
+			nil,
+		},
+		"regex constraint with slash": {
+			"/dates/:date{[0-9]{4}/[0-9]{2}}",
+			"/dates/2022/05",
+			true,
+			map[string]string{"date": "2022/05"},
+		},
+		"int type": {
+			"/users/:id<int>",
+			"/users/-7",
+			true,
+			map[string]string{"id": "-7"},
+		},
+		"uuid type": {
+			"/users/:id<uuid>",
+			"/users/0b5f1e7a-8c6d-4a0e-9d3b-6f1a2b3c4d5e",
+			true,
+			map[string]string{"id": "0b5f1e7a-8c6d-4a0e-9d3b-6f1a2b3c4d5e"},
+		},
+		"uuid type mismatch": {
+			"/users/:id<uuid>",
+			"/users/42",
+			false,
+			nil,
+		},
+		"optional present": {
+			"/posts/:page<uint>?",
+			"/posts/2",
+			true,
+			map[string]string{"page": "2"},
+		},
+		"optional absent": {
+			"/posts/:page<uint>?",
+			"/posts",
+			true,
+			map[string]string{},
+		},
+		"wildcard": {
+			"/files/*path",
+			"/files/a/b/c.txt",
+			true,
+			map[string]string{"path": "a/b/c.txt"},
+		},
+		"wildcard empty": {
+			"/files/*path",
+			"/files",
+			true,
+			map[string]string{"path": ""},
+		},
+		"partial match": {
+			"/users/:id",
+			"/users/1/posts",
+			false,
+			nil,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			var (
+				found  bool
+				params map[string]string
+			)
+
+			m := Mux{}
+			m.AddRoute("GET", tc.givePattern, func(w http.ResponseWriter, r *http.Request) {
+				found = true
+				params = Params(r.Context())
+			})
+
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest("GET", tc.givePath, nil))
+
+			assert.Equal(t, tc.wantFound, found)
+			assert.Equal(t, tc.wantParams, params)
+
+			if !tc.wantFound {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			}
+		})
+	}
+}
+
+func TestMux_GetParams(t *testing.T) {
+	m := Mux{}
+	m.AddRoute("GET", "/users/:id<int>/posts/:slug", func(http.ResponseWriter, *http.Request) {})
+
+	assert.Equal(t, map[string]string{"id": "1", "slug": "hello"}, m.GetParams("GET", "/users/1/posts/hello"))
+	assert.Nil(t, m.GetParams("POST", "/users/1/posts/hello"))
+
+	m.AddRoute("GET", "/orgs/:org", func(http.ResponseWriter, *http.Request) {}, WithHost("{tenant}.example.com"))
+
+	assert.Equal(t, map[string]string{"org": "acme"}, m.GetParams("GET", "/orgs/acme"), "requirements are ignored")
+}
+
+func TestMux_ServeHTTP_middlewareSeesParams(t *testing.T) {
+	var params map[string]string
+
+	m := Mux{}
+	m.AddRoute("GET", "/users/:id", func(http.ResponseWriter, *http.Request) {})
+	m.WithMiddleware(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			params = Params(r.Context())
+			next(w, r)
+		}
+	})
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+
+	assert.Equal(t, map[string]string{"id": "1"}, params)
+}
+
+func TestMux_ServeHTTP_options(t *testing.T) {
+	tests := map[string]struct {
+		giveOpts   []RouteOption
+		giveTarget string
+		giveHeader http.Header
+		wantFound  bool
+		wantParams map[string]string
+	}{
+		"host parameter": {
+			[]RouteOption{WithHost("{tenant}.api.example.com")},
+			"http://acme.api.example.com:8080/users/1",
+			nil,
+			true,
+			map[string]string{"tenant": "acme", "id": "1"},
+		},
+		"host wildcard": {
+			[]RouteOption{WithHost("*.example.com")},
+			"http://api.example.com/users/1",
+			nil,
+			true,
+			map[string]string{"id": "1"},
+		},
+		"host mismatch": {
+			[]RouteOption{WithHost("{tenant}.api.example.com")},
+			"http://api.example.com/users/1",
+			nil,
+			false,
+			nil,
+		},
+		"header media type": {
+			[]RouteOption{WithHeader("accept", "application/vnd.v2+json")},
+			"/users/1",
+			http.Header{"Accept": {"text/html, application/vnd.v2+json; q=0.9"}},
+			true,
+			map[string]string{"id": "1"},
+		},
+		"header mismatch": {
+			[]RouteOption{WithHeader("Accept", "application/vnd.v2+json")},
+			"/users/1",
+			http.Header{"Accept": {"application/json"}},
+			false,
+			nil,
+		},
+		"header media type excluded": {
+			[]RouteOption{WithHeader("Accept", "application/vnd.v2+json")},
+			"/users/1",
+			http.Header{"Accept": {"application/json, application/vnd.v2+json; q=0"}},
+			false,
+			nil,
+		},
+		"header presence": {
+			[]RouteOption{WithHeader("X-Tenant", "")},
+			"/users/1",
+			http.Header{"X-Tenant": {"acme"}},
+			true,
+			map[string]string{"id": "1"},
+		},
+		"query value": {
+			[]RouteOption{WithQuery("version", "2")},
+			"/users/1?version=2",
+			nil,
+			true,
+			map[string]string{"id": "1"},
+		},
+		"query mismatch": {
+			[]RouteOption{WithQuery("version", "2")},
+			"/users/1?version=1",
+			nil,
+			false,
+			nil,
+		},
+		"query presence": {
+			[]RouteOption{WithQuery("debug", "")},
+			"/users/1?debug",
+			nil,
+			true,
+			map[string]string{"id": "1"},
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			var (
+				found  bool
+				params map[string]string
+			)
+
+			m := Mux{}
+			m.AddRoute("GET", "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+				found = true
+				params = Params(r.Context())
+			}, tc.giveOpts...)
+
+			req := httptest.NewRequest("GET", tc.giveTarget, nil)
+			for k, v := range tc.giveHeader {
+				req.Header[k] = v
+			}
+
+			m.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tc.wantFound, found)
+			assert.Equal(t, tc.wantParams, params)
+		})
+	}
+}
+
+func TestMux_ServeHTTP_optionsOrder(t *testing.T) {
+	var version string
+
+	m := Mux{}
+	m.AddRoute("GET", "/users", func(http.ResponseWriter, *http.Request) {
+		version = "v2"
+	}, WithHeader("Accept", "application/vnd.v2+json"))
+	m.AddRoute("GET", "/users", func(http.ResponseWriter, *http.Request) {
+		version = "v1"
+	})
+
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.Header.Set("Accept", "application/vnd.v2+json")
+	m.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "v2", version)
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users", nil))
+
+	assert.Equal(t, "v1", version)
+}
+
+func TestMux_AddRoute_invalidOptions(t *testing.T) {
+	tests := map[string]RouteOption{
+		"empty host label":         WithHost("api..example.com"),
+		"invalid host param":       WithHost("{1x}.example.com"),
+		"duplicate host param":     WithHost("{id}.example.com"),
+		"empty header key":         WithHeader("", "value"),
+		"empty query key":          WithQuery("", "value"),
+		"duplicate host label var": WithHost("{a}.{a}.example.com"),
+	}
+
+	for name, test := range tests {
+		opt := test
+
+		t.Run(name, func(t *testing.T) {
+			m := Mux{}
+
+			require.Panics(t, func() {
+				m.AddRoute("GET", "/users/:id", func(http.ResponseWriter, *http.Request) {}, opt)
+			})
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// qValues parses the comma-separated header elements with their quality
+// values, such as "gzip;q=0.8, deflate". Elements are lower cased.
+func qValues(header string) map[string]float64 {
+	values := make(map[string]float64)
+
+	for _, elem := range strings.Split(header, ",") {
+		parts := strings.Split(elem, ";")
+
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+
+		for _, p := range parts[1:] {
+			p = strings.TrimSpace(p)
+			if !strings.HasPrefix(p, "q=") {
+				continue
+			}
+
+			if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+				q = v
+			}
+		}
+
+		values[name] = q
+	}
+
+	return values
+}
+
+// acceptsEncoding reports whether the request accepts the given content
+// coding according to the Accept-Encoding header.
+func acceptsEncoding(r *http.Request, coding string) bool {
+	return encodingQuality(qValues(strings.Join(r.Header.Values("Accept-Encoding"), ",")), coding) > 0
+}
+
+func encodingQuality(values map[string]float64, coding string) float64 {
+	if q, ok := values[coding]; ok {
+		return q
+	}
+
+	return values["*"]
+}
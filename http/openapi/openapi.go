@@ -0,0 +1,443 @@
+// Package openapi generates OpenAPI 3 documents from the routes registered
+// in http.Mux, reflecting over the Go types attached with http.WithDoc.
+//
+// Routes with a catch-all wildcard segment are not supported, as OpenAPI path
+// parameters cannot span several segments, and are omitted from documents.
+// The header and query requirements of routes are described as required
+// parameters. Routes sharing the method and path, distinguished only by the
+// host, header or query requirements, cannot be described by one operation,
+// so Generate fails for them.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	httpkit "github.com/diptanw/go-toolkit/http"
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+)
+
+// Version is the OpenAPI specification version of generated documents.
+const Version = "3.0.3"
+
+const contentTypeJSON = "application/json"
+
+// Document is the root object of the OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+// Info is the metadata about the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server is the API server, its URL may contain variables in braces.
+type Server struct {
+	URL       string                    `json:"url"`
+	Variables map[string]ServerVariable `json:"variables,omitempty"`
+}
+
+// ServerVariable is a variable for the server URL template.
+type ServerVariable struct {
+	Default string `json:"default"`
+}
+
+// PathItem describes the operations available on a single path.
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Trace   *Operation `json:"trace,omitempty"`
+}
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Servers     []Server             `json:"servers,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a single operation parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody describes a request body.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response from an API operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType provides the schema for the media type.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components holds the reusable schemas referenced from the document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Generate returns the OpenAPI document describing the given routes. A route
+// with optional segments is described on a path per each number of segments
+// present, and the operation IDs of the longer paths get a suffix with the
+// names of the optional parameters, such as "listPostsByPage". It returns an
+// error if several routes have the same method and path.
+func Generate(info Info, routes []httpkit.RouteInfo) (*Document, error) {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+
+	for _, r := range routes {
+		if hasWildcard(r) {
+			continue
+		}
+
+		for _, path := range pathTemplates(r) {
+			item, ok := doc.Paths[path.template]
+			if !ok {
+				item = &PathItem{}
+				doc.Paths[path.template] = item
+			}
+
+			op := g.operation(r, path.params)
+			if op.OperationID != "" {
+				op.OperationID += path.suffix
+			}
+
+			if !item.set(r.Method, op) {
+				return nil, fmt.Errorf("openapi: %s %s is described by several routes", r.Method, path.template)
+			}
+		}
+	}
+
+	if len(g.schemas) > 0 {
+		doc.Components = &Components{Schemas: g.schemas}
+	}
+
+	return doc, nil
+}
+
+// Handler returns a handler serving the OpenAPI document generated from the
+// routes registered in the given Mux. It responds with 500 Internal Server
+// Error if the document cannot be generated.
+func Handler(info Info, m *httpkit.Mux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc, err := Generate(info, m.Routes())
+		if err != nil {
+			jsonapi.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		jsonapi.Write(w, doc)
+	}
+}
+
+// Register adds a GET route to the Mux serving its OpenAPI document at the
+// given path.
+func Register(m *httpkit.Mux, path string, info Info) {
+	m.AddRoute(http.MethodGet, path, Handler(info, m))
+}
+
+// set sets the operation for the method, and reports false if there is one
+// already.
+func (p *PathItem) set(method string, op *Operation) bool {
+	var slot **Operation
+
+	switch method {
+	case http.MethodGet:
+		slot = &p.Get
+	case http.MethodPut:
+		slot = &p.Put
+	case http.MethodPost:
+		slot = &p.Post
+	case http.MethodDelete:
+		slot = &p.Delete
+	case http.MethodOptions:
+		slot = &p.Options
+	case http.MethodHead:
+		slot = &p.Head
+	case http.MethodPatch:
+		slot = &p.Patch
+	case http.MethodTrace:
+		slot = &p.Trace
+	default:
+		return true
+	}
+
+	if *slot != nil {
+		return false
+	}
+
+	*slot = op
+
+	return true
+}
+
+func (g *generator) operation(r httpkit.RouteInfo, pathParams []httpkit.RouteParam) *Operation {
+	op := &Operation{
+		Responses: make(map[string]*Response),
+	}
+
+	if r.Host != "" {
+		op.Servers = []Server{hostServer(r.Host)}
+	}
+
+	for _, p := range pathParams {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     p.Name,
+			In:       "path",
+			Required: true,
+			Schema:   paramSchema(p),
+		})
+	}
+
+	for _, h := range r.Headers {
+		op.Parameters = append(op.Parameters, requirement(h, "header", "Must contain %q."))
+	}
+
+	for _, q := range r.Queries {
+		op.Parameters = append(op.Parameters, requirement(q, "query", "Must be %q."))
+	}
+
+	if r.Doc != nil {
+		g.document(op, r.Doc)
+	}
+
+	if len(op.Responses) == 0 {
+		op.Responses[strconv.Itoa(http.StatusOK)] = &Response{
+			Description: http.StatusText(http.StatusOK),
+		}
+	}
+
+	return op
+}
+
+func (g *generator) document(op *Operation, doc *httpkit.RouteDoc) {
+	op.OperationID = doc.OperationID
+	op.Summary = doc.Summary
+	op.Description = doc.Description
+	op.Tags = doc.Tags
+
+	for _, pd := range doc.Params {
+		g.addParam(op, pd)
+	}
+
+	if doc.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				contentTypeJSON: {Schema: g.schemaOf(doc.Request)},
+			},
+		}
+	}
+
+	codes := make([]int, 0, len(doc.Responses))
+	for code := range doc.Responses {
+		codes = append(codes, code)
+	}
+
+	sort.Ints(codes)
+
+	for _, code := range codes {
+		resp := &Response{Description: http.StatusText(code)}
+
+		if v := doc.Responses[code]; v != nil {
+			resp.Content = map[string]MediaType{
+				contentTypeJSON: {Schema: g.schemaOf(v)},
+			}
+		}
+
+		op.Responses[strconv.Itoa(code)] = resp
+	}
+}
+
+func (g *generator) addParam(op *Operation, pd httpkit.ParamDoc) {
+	in := pd.In
+	if in == "" {
+		in = "query"
+	}
+
+	for _, p := range op.Parameters {
+		if p.Name == pd.Name && p.In == in {
+			p.Description = pd.Description
+
+			if pd.Type != nil {
+				p.Schema = g.schemaOf(pd.Type)
+			}
+
+			return
+		}
+	}
+
+	// Path parameters not present in the path template are dropped, as the
+	// route may have optional segments.
+	if in == "path" {
+		return
+	}
+
+	schema := &Schema{Type: "string"}
+	if pd.Type != nil {
+		schema = g.schemaOf(pd.Type)
+	}
+
+	op.Parameters = append(op.Parameters, &Parameter{
+		Name:        pd.Name,
+		In:          in,
+		Description: pd.Description,
+		Required:    pd.Required,
+		Schema:      schema,
+	})
+}
+
+// requirement returns the required parameter for the header or query
+// requirement of the route.
+func requirement(req httpkit.RouteRequirement, in, format string) *Parameter {
+	p := &Parameter{
+		Name:     req.Key,
+		In:       in,
+		Required: true,
+		Schema:   &Schema{Type: "string"},
+	}
+
+	if req.Value != "" {
+		p.Description = fmt.Sprintf(format, req.Value)
+	}
+
+	return p
+}
+
+type pathTemplate struct {
+	template string
+	params   []httpkit.RouteParam
+	// suffix distinguishes the operation ID of the path with optional
+	// parameters.
+	suffix string
+}
+
+func hasWildcard(r httpkit.RouteInfo) bool {
+	for _, p := range r.Params {
+		if p.Wildcard {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pathTemplates converts the route pattern to OpenAPI path templates, one
+// per each number of optional segments present.
+func pathTemplates(r httpkit.RouteInfo) []pathTemplate {
+	var pathParams []httpkit.RouteParam
+
+	for _, p := range r.Params {
+		if !p.Host {
+			pathParams = append(pathParams, p)
+		}
+	}
+
+	var (
+		base      pathTemplate
+		optionals []pathTemplate
+	)
+
+	// The pattern is valid, as the route is registered.
+	segments, _ := httpkit.SplitPattern(r.Pattern)
+
+	for _, seg := range segments {
+		if !strings.HasPrefix(seg, ":") {
+			base.template += "/" + seg
+			continue
+		}
+
+		p := pathParams[0]
+		pathParams = pathParams[1:]
+
+		if !p.Optional {
+			base.template += "/{" + p.Name + "}"
+			base.params = append(base.params, p)
+
+			continue
+		}
+
+		last, suffix := base, "By"
+		if len(optionals) > 0 {
+			last = optionals[len(optionals)-1]
+			suffix = last.suffix + "And"
+		}
+
+		optionals = append(optionals, pathTemplate{
+			template: last.template + "/{" + p.Name + "}",
+			params:   append(append([]httpkit.RouteParam{}, last.params...), p),
+			suffix:   suffix + strings.ToUpper(p.Name[:1]) + p.Name[1:],
+		})
+	}
+
+	if base.template == "" {
+		base.template = "/"
+	}
+
+	return append([]pathTemplate{base}, optionals...)
+}
+
+// hostServer converts the route host pattern to the server URL template.
+func hostServer(host string) Server {
+	labels := strings.Split(host, ".")
+	srv := Server{}
+
+	for i, label := range labels {
+		name := ""
+
+		switch {
+		case label == "*":
+			name = "label" + strconv.Itoa(i)
+			labels[i] = "{" + name + "}"
+		case strings.HasPrefix(label, "{"):
+			name = strings.Trim(label, "{}")
+		default:
+			continue
+		}
+
+		if srv.Variables == nil {
+			srv.Variables = make(map[string]ServerVariable)
+		}
+
+		srv.Variables[name] = ServerVariable{Default: name}
+	}
+
+	srv.URL = "//" + strings.Join(labels, ".")
+
+	return srv
+}
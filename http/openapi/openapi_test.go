@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	httpkit "github.com/diptanw/go-toolkit/http"
+)
+
+type user struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Manager   *user     `json:"manager,omitempty"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"createdAt"`
+	Secret    string    `json:"-"`
+	audit
+}
+
+type audit struct {
+	Version int `json:"version"`
+}
+
+func TestGenerate(t *testing.T) {
+	m := httpkit.Mux{}
+	noop := func(http.ResponseWriter, *http.Request) {}
+
+	m.AddRoute("GET", "/users/:id<uuid>", noop, httpkit.WithDoc(httpkit.RouteDoc{
+		Summary:   "Get user",
+		Responses: map[int]interface{}{200: user{}, 404: nil},
+		Params: []httpkit.ParamDoc{
+			{Name: "id", In: "path", Description: "user id"},
+			{Name: "fields", Description: "sparse fields"},
+		},
+	}))
+	m.AddRoute("POST", "/users", noop, httpkit.WithDoc(httpkit.RouteDoc{
+		Request:   &user{},
+		Responses: map[int]interface{}{201: user{}},
+	}))
+	m.AddRoute("GET", "/posts/:page<uint>?", noop, httpkit.WithHost("{tenant}.example.com"), httpkit.WithDoc(httpkit.RouteDoc{
+		OperationID: "listPosts",
+	}))
+	m.AddRoute("GET", "/files/*path", noop)
+
+	doc, err := Generate(Info{Title: "test", Version: "1.0"}, m.Routes())
+	require.NoError(t, err)
+
+	require.Contains(t, doc.Paths, "/users/{id}")
+	require.Contains(t, doc.Paths, "/users")
+	require.Contains(t, doc.Paths, "/posts")
+	require.Contains(t, doc.Paths, "/posts/{page}")
+	assert.NotContains(t, doc.Paths, "/files/{path}", "wildcard routes are not supported")
+
+	get := doc.Paths["/users/{id}"].Get
+	require.NotNil(t, get)
+	assert.Equal(t, "Get user", get.Summary)
+	assert.Equal(t, []*Parameter{
+		{Name: "id", In: "path", Description: "user id", Required: true, Schema: &Schema{Type: "string", Format: "uuid"}},
+		{Name: "fields", In: "query", Description: "sparse fields", Schema: &Schema{Type: "string"}},
+	}, get.Parameters)
+	assert.Equal(t, "#/components/schemas/user", get.Responses["200"].Content[contentTypeJSON].Schema.Ref)
+	assert.Nil(t, get.Responses["404"].Content)
+
+	post := doc.Paths["/users"].Post
+	require.NotNil(t, post)
+	assert.Equal(t, "#/components/schemas/user", post.RequestBody.Content[contentTypeJSON].Schema.Ref)
+
+	page := doc.Paths["/posts/{page}"].Get
+	require.NotNil(t, page)
+	assert.Equal(t, "//{tenant}.example.com", page.Servers[0].URL)
+	assert.Equal(t, "OK", page.Responses["200"].Description)
+	assert.Equal(t, "listPostsByPage", page.OperationID)
+	assert.Equal(t, "listPosts", doc.Paths["/posts"].Get.OperationID)
+
+	schema := doc.Components.Schemas["user"]
+	require.NotNil(t, schema)
+	assert.ElementsMatch(t, []string{"id", "tags", "createdAt", "version"}, schema.Required)
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, schema.Properties["createdAt"])
+	assert.Equal(t, "#/components/schemas/user", schema.Properties["manager"].Ref)
+	assert.NotContains(t, schema.Properties, "Secret")
+}
+
+func TestGenerate_Requirements(t *testing.T) {
+	m := httpkit.Mux{}
+	noop := func(http.ResponseWriter, *http.Request) {}
+
+	m.AddRoute("GET", "/users", noop,
+		httpkit.WithHeader("Accept", "application/vnd.v2+json"), httpkit.WithQuery("active", ""))
+
+	doc, err := Generate(Info{Title: "test", Version: "1.0"}, m.Routes())
+	require.NoError(t, err)
+
+	assert.Equal(t, []*Parameter{
+		{Name: "Accept", In: "header", Description: `Must contain "application/vnd.v2+json".`, Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "active", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+	}, doc.Paths["/users"].Get.Parameters)
+
+	m.AddRoute("GET", "/users", noop, httpkit.WithHeader("Accept", "application/vnd.v1+json"))
+
+	_, err = Generate(Info{Title: "test", Version: "1.0"}, m.Routes())
+	assert.EqualError(t, err, "openapi: GET /users is described by several routes")
+
+	rec := httptest.NewRecorder()
+	Handler(Info{}, &m)(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestRegister(t *testing.T) {
+	m := httpkit.Mux{}
+	m.AddRoute("GET", "/users", func(http.ResponseWriter, *http.Request) {})
+
+	Register(&m, "/openapi.json", Info{Title: "test", Version: "1.0"})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc Document
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/users")
+	assert.Contains(t, doc.Paths, "/openapi.json")
+}
@@ -0,0 +1,217 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	httpkit "github.com/diptanw/go-toolkit/http"
+)
+
+// Schema is a subset of the OpenAPI schema object sufficient for describing
+// JSON encoded Go types.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+func (g *generator) schemaOf(v interface{}) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+// schema returns the schema for the given type, named struct types are
+// registered in components and referenced.
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType):
+		// Custom JSON representation, unknown to reflection.
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + g.register(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// register adds the named struct type to components and returns its name.
+func (g *generator) register(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, ok := g.schemas[name]; ok {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+
+	// Reserve the name before descending into fields to allow recursive types.
+	g.names[t] = name
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+
+	return name
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		name, omitempty, skip := jsonName(f)
+		if skip {
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		// Embedded structs without a name are flattened by encoding/json.
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded := g.structSchema(ft)
+
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+
+			s.Required = append(s.Required, embedded.Required...)
+
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.schema(f.Type)
+		if f.Type.Kind() == reflect.Ptr && prop.Ref == "" {
+			prop.Nullable = true
+		}
+
+		s.Properties[name] = prop
+
+		if !omitempty && f.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
+
+func jsonName(f reflect.StructField) (name string, omitempty, skip bool) {
+	tag, ok := f.Tag.Lookup("json")
+	if !ok {
+		return "", false, false
+	}
+
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+
+	return parts[0], omitempty, false
+}
+
+// paramSchema returns the schema for the route parameter based on its type
+// or constraint.
+func paramSchema(p httpkit.RouteParam) *Schema {
+	switch p.Type {
+	case "int":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "uint":
+		minimum := 0.0
+		return &Schema{Type: "integer", Format: "int64", Minimum: &minimum}
+	case "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	}
+
+	if p.Pattern == "[^/]+" {
+		return &Schema{Type: "string"}
+	}
+
+	return &Schema{Type: "string", Pattern: "^(?:" + p.Pattern + ")$"}
+}
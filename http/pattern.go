@@ -0,0 +1,214 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// paramTypes maps the named parameter types to their regular expressions.
+var paramTypes = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+	"hex":   `[0-9a-fA-F]+`,
+	"slug":  `[a-z0-9]+(?:-[a-z0-9]+)*`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+const defaultParamExpr = `[^/]+`
+
+var paramNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// param is a route pattern parameter.
+type param struct {
+	name     string
+	typ      string
+	expr     string
+	optional bool
+	wildcard bool
+}
+
+// compilePattern parses the route pattern and compiles it into an anchored
+// regular expression with a capture group per parameter.
+func compilePattern(pattern string) (*regexp.Regexp, []param, error) {
+	segments, err := SplitPattern(pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		expr     strings.Builder
+		params   []param
+		optional bool
+	)
+
+	names := make(map[string]bool)
+
+	expr.WriteString("^")
+
+	for i, seg := range segments {
+		p, ok, err := parseSegment(seg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !ok {
+			if optional {
+				return nil, nil, fmt.Errorf("segment %q follows an optional parameter", seg)
+			}
+
+			expr.WriteString("/" + regexp.QuoteMeta(seg))
+
+			continue
+		}
+
+		if names[p.name] {
+			return nil, nil, fmt.Errorf("duplicate parameter %q", p.name)
+		}
+
+		names[p.name] = true
+
+		switch {
+		case p.wildcard:
+			if i != len(segments)-1 {
+				return nil, nil, fmt.Errorf("wildcard %q must be the last segment", p.name)
+			}
+
+			expr.WriteString("(?:/(" + p.expr + "))?")
+		case p.optional:
+			optional = true
+
+			expr.WriteString("(?:/(" + p.expr + "))?")
+		default:
+			if optional {
+				return nil, nil, fmt.Errorf("parameter %q follows an optional parameter", p.name)
+			}
+
+			expr.WriteString("/(" + p.expr + ")")
+		}
+
+		params = append(params, p)
+	}
+
+	expr.WriteString("$")
+
+	regex, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return regex, params, nil
+}
+
+// SplitPattern splits the route pattern into its path segments, by slashes
+// that are not enclosed in constraint braces. The leading slash is ignored.
+func SplitPattern(pattern string) ([]string, error) {
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var (
+		segments []string
+		depth    int
+		start    int
+	)
+
+	for i, c := range pattern {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth < 0 {
+				return nil, errors.New("unbalanced braces")
+			}
+		case '/':
+			if depth == 0 {
+				segments = append(segments, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, errors.New("unbalanced braces")
+	}
+
+	return append(segments, pattern[start:]), nil
+}
+
+// parseSegment parses a single path segment. Returns false if the segment is
+// a literal.
+func parseSegment(seg string) (param, bool, error) {
+	if strings.HasPrefix(seg, "*") {
+		p := param{name: seg[1:], expr: ".*", wildcard: true}
+		if !paramNameRegex.MatchString(p.name) {
+			return param{}, false, fmt.Errorf("invalid wildcard name %q", p.name)
+		}
+
+		return p, true, nil
+	}
+
+	if !strings.HasPrefix(seg, ":") {
+		return param{}, false, nil
+	}
+
+	p := param{expr: defaultParamExpr}
+	def := seg[1:]
+
+	if strings.HasSuffix(def, "?") {
+		p.optional = true
+		def = strings.TrimSuffix(def, "?")
+	}
+
+	switch i := strings.IndexAny(def, "{<"); {
+	case i < 0:
+		p.name = def
+	case def[i] == '{':
+		if !strings.HasSuffix(def, "}") {
+			return param{}, false, fmt.Errorf("invalid constraint in %q", seg)
+		}
+
+		p.name, p.expr = def[:i], def[i+1:len(def)-1]
+
+		if err := validateExpr(p.expr); err != nil {
+			return param{}, false, fmt.Errorf("parameter %q: %w", p.name, err)
+		}
+	default:
+		if !strings.HasSuffix(def, ">") {
+			return param{}, false, fmt.Errorf("invalid type in %q", seg)
+		}
+
+		p.name, p.typ = def[:i], def[i+1:len(def)-1]
+
+		expr, ok := paramTypes[p.typ]
+		if !ok {
+			return param{}, false, fmt.Errorf("parameter %q: unknown type %q", p.name, p.typ)
+		}
+
+		p.expr = expr
+	}
+
+	if !paramNameRegex.MatchString(p.name) {
+		return param{}, false, fmt.Errorf("invalid parameter name %q", p.name)
+	}
+
+	return p, true, nil
+}
+
+func validateExpr(expr string) error {
+	if expr == "" {
+		return errors.New("empty constraint")
+	}
+
+	regex, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return err
+	}
+
+	if regex.NumSubexp() > 0 {
+		return errors.New("capturing groups are not allowed, use (?:...)")
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	httpkit "github.com/diptanw/go-toolkit/http"
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+)
+
+// KeyFunc returns the client key the request is limited by. Requests with an
+// empty key are not limited.
+type KeyFunc func(*http.Request) string
+
+// Middleware returns a middleware func that limits requests per client key.
+// Responses carry RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers, and requests over the limit are responded with 429 Too Many
+// Requests and Retry-After header. Requests are allowed if the store fails.
+func Middleware(l *Limiter, key KeyFunc) httpkit.MiddlewareFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := l.Allow(r.Context(), k)
+			if err != nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+			if !res.Allowed {
+				header.Set("Retry-After", ceilSeconds(res.RetryAfter))
+				jsonapi.WriteError(w, http.StatusTooManyRequests)
+
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		}
+	}
+}
+
+// ByIP returns the KeyFunc that limits requests by the client IP address.
+// If the request comes from one of the trusted proxies, given as IP addresses
+// or CIDR ranges, the client address is taken from X-Forwarded-For header.
+// ByIP panics if a proxy address is invalid.
+func ByIP(trustedProxies ...string) KeyFunc {
+	trusted := make([]*net.IPNet, 0, len(trustedProxies))
+
+	for _, p := range trustedProxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			panic("ratelimit: invalid trusted proxy " + p + ": " + err.Error())
+		}
+
+		trusted = append(trusted, ipNet)
+	}
+
+	return func(r *http.Request) string {
+		return ClientIP(r, trusted)
+	}
+}
+
+// ByHeader returns the KeyFunc that limits requests by the value of the given
+// header, such as an API key. Requests without the header are not limited.
+func ByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return name + ":" + v
+		}
+
+		return ""
+	}
+}
+
+// ClientIP returns the client IP address of the request. X-Forwarded-For
+// header is only considered when the request comes from a trusted proxy, and
+// it is walked from right to left skipping the trusted proxy addresses.
+func ClientIP(r *http.Request, trusted []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !isTrusted(net.ParseIP(host), trusted) {
+		return host
+	}
+
+	var hops []string
+
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			break
+		}
+
+		host = ip.String()
+
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+
+	return host
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
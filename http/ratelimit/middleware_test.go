@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	l := NewLimiter(TokenBucket{Limit: 1, Period: time.Minute}, nil)
+	h := Middleware(l, ByHeader("X-API-Key"))(func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+
+		rec := httptest.NewRecorder()
+		h(rec, req)
+
+		return rec
+	}
+
+	rec := serve("a")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", rec.Header().Get("RateLimit-Reset"))
+
+	rec = serve("a")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"errors":["Too Many Requests"]}`, rec.Body.String())
+
+	rec = serve("b")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve("")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+}
+
+func TestClientIP(t *testing.T) {
+	tests := map[string]struct {
+		giveTrusted []string
+		giveRemote  string
+		giveXFF     []string
+		wantIP      string
+	}{
+		"no proxy": {
+			nil,
+			"203.0.113.1:1234",
+			[]string{"198.51.100.1"},
+			"203.0.113.1",
+		},
+		"trusted proxy": {
+			[]string{"10.0.0.0/8"},
+			"10.0.0.1:1234",
+			[]string{"198.51.100.1, 10.0.0.2"},
+			"198.51.100.1",
+		},
+		"spoofed header behind trusted proxy": {
+			[]string{"10.0.0.1"},
+			"10.0.0.1:1234",
+			[]string{"1.1.1.1", "198.51.100.1"},
+			"198.51.100.1",
+		},
+		"all hops trusted": {
+			[]string{"10.0.0.0/8"},
+			"10.0.0.1:1234",
+			[]string{"10.0.0.3, 10.0.0.2"},
+			"10.0.0.3",
+		},
+		"invalid hop": {
+			[]string{"10.0.0.0/8"},
+			"10.0.0.1:1234",
+			[]string{"garbage"},
+			"10.0.0.1",
+		},
+		"ipv6": {
+			[]string{"::1"},
+			"[::1]:1234",
+			[]string{"2001:db8::1"},
+			"2001:db8::1",
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.giveRemote
+			req.Header["X-Forwarded-For"] = tc.giveXFF
+
+			assert.Equal(t, tc.wantIP, ByIP(tc.giveTrusted...)(req))
+		})
+	}
+}
+
+func TestByIP_invalid(t *testing.T) {
+	assert.Panics(t, func() {
+		ByIP("not-an-ip")
+	})
+
+	assert.Equal(t, "", ClientIP(&http.Request{}, []*net.IPNet{}))
+}
@@ -0,0 +1,240 @@
+// Package ratelimit provides per-client rate limiting for HTTP handlers, with
+// token bucket and sliding window algorithms and a pluggable state store.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// Result is the outcome of the rate limit check.
+type Result struct {
+	// Allowed is true if the request is within the limit.
+	Allowed bool
+	// Limit is the maximum number of requests in the quota.
+	Limit int
+	// Remaining is the number of requests left in the quota.
+	Remaining int
+	// Reset is the time until the quota is fully restored.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, set only
+	// when the request is not allowed.
+	RetryAfter time.Duration
+}
+
+// State is the per-key limiter state persisted in Store.
+type State struct {
+	Value float64
+	Prev  float64
+	Time  time.Time
+}
+
+// Algorithm is a rate limiting algorithm.
+type Algorithm interface {
+	// Take consumes a single request from the state at the given time.
+	Take(now time.Time, s State) (State, Result)
+	// TTL is the duration of inactivity after which the state is no longer
+	// relevant and can be evicted.
+	TTL() time.Duration
+}
+
+// Limiter checks requests against the algorithm using the state from Store.
+type Limiter struct {
+	algo  Algorithm
+	store Store
+	now   func() time.Time
+}
+
+// NewLimiter returns a new instance of Limiter. A new InMemory store is used
+// if the store is nil. NewLimiter panics if TokenBucket or SlidingWindow has
+// a non-positive limit, period or window.
+func NewLimiter(algo Algorithm, store Store) *Limiter {
+	if v, ok := algo.(interface{ validate() error }); ok {
+		if err := v.validate(); err != nil {
+			panic("ratelimit: " + err.Error())
+		}
+	}
+
+	if store == nil {
+		store = NewInMemory(0)
+	}
+
+	return &Limiter{
+		algo:  algo,
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow consumes a single request for the given key.
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	var res Result
+
+	err := l.store.Update(ctx, key, l.algo.TTL(), func(s State) State {
+		s, res = l.algo.Take(l.now(), s)
+		return s
+	})
+
+	return res, err
+}
+
+// TokenBucket is the algorithm that allows bursts of up to Burst requests,
+// and refills the bucket at the rate of Limit requests per Period.
+type TokenBucket struct {
+	Limit  int
+	Period time.Duration
+	// Burst is the bucket capacity, defaults to Limit.
+	Burst int
+}
+
+// Take consumes a token from the bucket.
+func (b TokenBucket) Take(now time.Time, s State) (State, Result) {
+	capacity := float64(b.capacity())
+	rate := float64(b.Limit) / b.Period.Seconds()
+
+	if s.Time.IsZero() {
+		s.Value, s.Time = capacity, now
+	}
+
+	if elapsed := now.Sub(s.Time).Seconds(); elapsed > 0 {
+		s.Value = math.Min(capacity, s.Value+elapsed*rate)
+		s.Time = now
+	}
+
+	res := Result{Limit: int(capacity)}
+
+	if s.Value >= 1 {
+		s.Value--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - s.Value) / rate)
+	}
+
+	res.Remaining = int(s.Value)
+	res.Reset = seconds((capacity - s.Value) / rate)
+
+	return s, res
+}
+
+// TTL returns the time to refill the empty bucket.
+func (b TokenBucket) TTL() time.Duration {
+	return seconds(float64(b.capacity()) / (float64(b.Limit) / b.Period.Seconds()))
+}
+
+func (b TokenBucket) validate() error {
+	switch {
+	case b.Limit <= 0:
+		return errors.New("token bucket limit must be positive")
+	case b.Period <= 0:
+		return errors.New("token bucket period must be positive")
+	case b.Burst < 0:
+		return errors.New("token bucket burst must not be negative")
+	default:
+		return nil
+	}
+}
+
+func (b TokenBucket) capacity() int {
+	if b.Burst > 0 {
+		return b.Burst
+	}
+
+	return b.Limit
+}
+
+// SlidingWindow is the algorithm that allows Limit requests per Window. The
+// count is approximated by weighting the previous fixed window count with
+// its overlap with the sliding window.
+type SlidingWindow struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Take counts the request in the current window.
+func (w SlidingWindow) Take(now time.Time, s State) (State, Result) {
+	start := now.Truncate(w.Window)
+
+	switch {
+	case s.Time.Equal(start):
+	case s.Time.Add(w.Window).Equal(start):
+		s.Prev, s.Value, s.Time = s.Value, 0, start
+	default:
+		s.Prev, s.Value, s.Time = 0, 0, start
+	}
+
+	elapsed := now.Sub(start)
+	count := s.Prev*(1-float64(elapsed)/float64(w.Window)) + s.Value
+	limit := float64(w.Limit)
+
+	res := Result{
+		Limit: w.Limit,
+		Reset: w.Window - elapsed,
+	}
+
+	if count+1 <= limit {
+		s.Value++
+		count++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = w.retryAfter(s, elapsed)
+	}
+
+	// Requests of the current window are weighted until the end of the next.
+	if s.Value > 0 {
+		res.Reset += w.Window
+	}
+
+	res.Remaining = int(math.Max(0, math.Floor(limit-count)))
+
+	return s, res
+}
+
+// retryAfter returns the time until the weighted count drops enough to allow
+// another request.
+func (w SlidingWindow) retryAfter(s State, elapsed time.Duration) time.Duration {
+	limit := float64(w.Limit)
+	untilNext := w.Window - elapsed
+
+	// The current window is full, it becomes the previous one and the weighted
+	// count must decay within the next window.
+	if s.Value+1 > limit {
+		return untilNext + decay(s.Value, limit-1, w.Window)
+	}
+
+	if t := decay(s.Prev, limit-s.Value-1, w.Window); t > elapsed {
+		return t - elapsed
+	}
+
+	return untilNext
+}
+
+// decay solves count*(1-t/window) <= target for t.
+func decay(count, target float64, window time.Duration) time.Duration {
+	if count <= target {
+		return 0
+	}
+
+	return time.Duration((1 - target/count) * float64(window))
+}
+
+// TTL returns the duration of two windows.
+func (w SlidingWindow) TTL() time.Duration {
+	return 2 * w.Window
+}
+
+func (w SlidingWindow) validate() error {
+	switch {
+	case w.Limit <= 0:
+		return errors.New("sliding window limit must be positive")
+	case w.Window <= 0:
+		return errors.New("sliding window must be positive")
+	default:
+		return nil
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,180 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	b := TokenBucket{Limit: 2, Period: time.Second, Burst: 3}
+	now := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	var (
+		s   State
+		res Result
+	)
+
+	for i := 0; i < 3; i++ {
+		s, res = b.Take(now, s)
+		require.True(t, res.Allowed, i)
+	}
+
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 3, res.Limit)
+	assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	s, res = b.Take(now, s)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	s, res = b.Take(now.Add(500*time.Millisecond), s)
+	assert.True(t, res.Allowed)
+
+	_, res = b.Take(now.Add(time.Hour), s)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+
+	assert.Equal(t, 1500*time.Millisecond, b.TTL())
+}
+
+func TestSlidingWindow_Take(t *testing.T) {
+	w := SlidingWindow{Limit: 4, Window: time.Minute}
+	start := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	var (
+		s   State
+		res Result
+	)
+
+	for i := 0; i < 4; i++ {
+		s, res = w.Take(start.Add(30*time.Second), s)
+		require.True(t, res.Allowed, i)
+	}
+
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 90*time.Second, res.Reset)
+
+	s, res = w.Take(start.Add(30*time.Second), s)
+	assert.False(t, res.Allowed)
+	// The count decays below the limit 15s into the next window.
+	assert.Equal(t, 45*time.Second, res.RetryAfter)
+
+	// 4 requests from the previous window weighted at 0.5.
+	s, res = w.Take(start.Add(90*time.Second), s)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	s, res = w.Take(start.Add(90*time.Second), s)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	s, res = w.Take(start.Add(90*time.Second), s)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 15*time.Second, res.RetryAfter)
+
+	_, res = w.Take(start.Add(10*time.Minute), s)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 3, res.Remaining)
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l := NewLimiter(SlidingWindow{Limit: 1, Window: time.Hour}, nil)
+
+	res, err := l.Allow(context.Background(), "a")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	res, _ = l.Allow(context.Background(), "a")
+	assert.False(t, res.Allowed)
+
+	res, _ = l.Allow(context.Background(), "b")
+	assert.True(t, res.Allowed)
+}
+
+func TestNewLimiter_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		give Algorithm
+		want string
+	}{
+		"zero limit": {
+			TokenBucket{Period: time.Second},
+			"ratelimit: token bucket limit must be positive",
+		},
+		"zero period": {
+			TokenBucket{Limit: 10},
+			"ratelimit: token bucket period must be positive",
+		},
+		"negative burst": {
+			TokenBucket{Limit: 10, Period: time.Second, Burst: -1},
+			"ratelimit: token bucket burst must not be negative",
+		},
+		"zero window limit": {
+			SlidingWindow{Window: time.Second},
+			"ratelimit: sliding window limit must be positive",
+		},
+		"zero window": {
+			SlidingWindow{Limit: 10},
+			"ratelimit: sliding window must be positive",
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.PanicsWithValue(t, tc.want, func() {
+				NewLimiter(tc.give, nil)
+			})
+		})
+	}
+}
+
+func TestInMemory_Update(t *testing.T) {
+	now := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	s := NewInMemory(2)
+	s.now = func() time.Time { return now }
+
+	incr := func(st State) State {
+		st.Value++
+		return st
+	}
+
+	var got State
+
+	ctx := context.Background()
+
+	s.Update(ctx, "a", time.Second, incr)
+	s.Update(ctx, "a", time.Second, func(st State) State {
+		got = st
+		return st
+	})
+
+	assert.Equal(t, 1.0, got.Value)
+
+	now = now.Add(2 * time.Second)
+
+	s.Update(ctx, "a", time.Second, func(st State) State {
+		got = st
+		return st
+	})
+
+	assert.Equal(t, State{}, got, "expired state is reset")
+
+	s.Update(ctx, "b", 2*time.Second, incr)
+	s.Update(ctx, "c", 3*time.Second, incr)
+
+	assert.Equal(t, 2, s.Len())
+
+	s.Update(ctx, "a", time.Second, func(st State) State {
+		got = st
+		return st
+	})
+
+	assert.Equal(t, State{}, got, "least recently updated key is evicted")
+}
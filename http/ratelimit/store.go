@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store is a pluggable storage for the limiter state.
+type Store interface {
+	// Update atomically applies fn to the state stored for the key and
+	// stores the result for the given TTL. Zero State is passed to fn if the
+	// key is not found or expired.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(State) State) error
+}
+
+const sweepInterval = time.Minute
+
+// InMemory is the in-memory Store, expired states are evicted periodically.
+type InMemory struct {
+	maxKeys   int
+	entries   map[string]*list.Element
+	order     *list.List
+	lastSweep time.Time
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+type entry struct {
+	key     string
+	state   State
+	expires time.Time
+}
+
+// NewInMemory returns a new instance of InMemory store. When maxKeys is
+// positive and the number of keys exceeds it, the least recently updated keys
+// are evicted.
+func NewInMemory(maxKeys int) *InMemory {
+	return &InMemory{
+		maxKeys: maxKeys,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Update applies fn to the state stored for the key.
+func (s *InMemory) Update(_ context.Context, key string, ttl time.Duration, fn func(State) State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	el, ok := s.entries[key]
+	if !ok {
+		el = s.order.PushFront(&entry{key: key})
+		s.entries[key] = el
+	} else {
+		s.order.MoveToFront(el)
+	}
+
+	e := el.Value.(*entry)
+	if now.After(e.expires) {
+		e.state = State{}
+	}
+
+	e.state = fn(e.state)
+	e.expires = now.Add(ttl)
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	for s.maxKeys > 0 && len(s.entries) > s.maxKeys {
+		s.remove(s.order.Back())
+	}
+
+	return nil
+}
+
+// Len returns the number of stored keys.
+func (s *InMemory) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+func (s *InMemory) sweep(now time.Time) {
+	s.lastSweep = now
+
+	for _, el := range s.entries {
+		if now.After(el.Value.(*entry).expires) {
+			s.remove(el)
+		}
+	}
+}
+
+func (s *InMemory) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.entries, el.Value.(*entry).key)
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+// PanicReporter is a hook for reporting recovered panics, for example to an
+// error tracking service.
+type PanicReporter func(r *http.Request, recovered interface{}, stack []byte)
+
+// Recover returns a middleware func that recovers from panics in handlers.
+// The panic is logged with the stack trace and reported with the optional
+// reporter. Unless the response header was already sent, 500 Internal Server
+// Error is written as jsonapi.ErrorResponse. The http.ErrAbortHandler panic
+// is propagated to abort the response.
+func Recover(log logger.Logger, report PanicReporter) MiddlewareFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ww, rec := wrapWriter(w)
+
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				stack := debug.Stack()
+
+				log.WithContext(r.Context()).Errorf("panic: %v\n%s", v, stack)
+
+				if report != nil {
+					report(r, v, stack)
+				}
+
+				if !rec.wroteHeader {
+					jsonapi.WriteError(w, http.StatusInternalServerError)
+				}
+			}()
+
+			h.ServeHTTP(ww, r)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+func TestRecover(t *testing.T) {
+	tests := map[string]struct {
+		giveHandler http.HandlerFunc
+		wantCode    int
+		wantBody    string
+		wantReport  interface{}
+	}{
+		"no panic": {
+			func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "ok")
+			},
+			http.StatusOK,
+			"ok",
+			nil,
+		},
+		"panic": {
+			func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+			http.StatusInternalServerError,
+			`{"errors":["Internal Server Error"]}`,
+			"boom",
+		},
+		"panic after headers sent": {
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("boom")
+			},
+			http.StatusAccepted,
+			"",
+			"boom",
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			var (
+				buf      bytes.Buffer
+				reported interface{}
+			)
+
+			mw := Recover(logger.New(&buf, logger.Error), func(r *http.Request, v interface{}, stack []byte) {
+				reported = v
+				assert.NotEmpty(t, stack)
+			})
+
+			rec := httptest.NewRecorder()
+			mw(tc.giveHandler)(rec, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+			assert.Equal(t, tc.wantReport, reported)
+
+			if tc.wantReport != nil {
+				assert.Contains(t, buf.String(), "ERR: panic: boom")
+			}
+		})
+	}
+}
+
+func TestRecover_abortHandler(t *testing.T) {
+	h := Recover(logger.New(io.Discard, logger.Error), nil)(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/diptanw/go-toolkit/internal/random"
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+// RequestIDHeader is the header carrying the request correlation identifier.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLen = 128
+
+// RequestID is a middleware func that reads the request identifier from the
+// X-Request-ID header, or generates a new one if it is missing or malformed.
+// The identifier is stored in the request context, set to the response header
+// and added to the logger fields under the "request_id" key, so that
+// logger.Logger.WithContext includes it in every log line.
+func RequestID(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := ContextWithRequestID(r.Context(), id)
+		ctx = logger.ContextWith(ctx, "request_id", id)
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request identifier.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request identifier carried by ctx, or an
+// empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithRequestID returns a copy of http.Client with the transport forwarding
+// the request identifier from the request context in the X-Request-ID header.
+// Requests that already have the header are sent unchanged.
+func WithRequestID(client *http.Client) *http.Client {
+	cp := *client
+	if cp.Transport == nil {
+		cp.Transport = http.DefaultTransport
+	}
+
+	cp.Transport = requestIDTransport{
+		next: cp.Transport,
+	}
+
+	return &cp
+}
+
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := RequestIDFromContext(req.Context())
+	if id == "" || req.Header.Get(RequestIDHeader) != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	// RoundTripper should not modify the request.
+	req = req.Clone(req.Context())
+	req.Header.Set(RequestIDHeader, id)
+
+	return t.next.RoundTrip(req)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	const size = 16
+
+	return hex.EncodeToString(random.Bytes(size))
+}
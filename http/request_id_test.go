@@ -0,0 +1,113 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := map[string]struct {
+		giveID   string
+		wantSame bool
+	}{
+		"forwarded": {
+			"abc-123",
+			true,
+		},
+		"missing": {
+			"",
+			false,
+		},
+		"malformed": {
+			"abc 123\n",
+			false,
+		},
+		"too long": {
+			strings.Repeat("a", 129),
+			false,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			var ctx context.Context
+
+			h := RequestID(func(w http.ResponseWriter, r *http.Request) {
+				ctx = r.Context()
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.giveID != "" {
+				req.Header.Set(RequestIDHeader, tc.giveID)
+			}
+
+			rec := httptest.NewRecorder()
+			h(rec, req)
+
+			id := RequestIDFromContext(ctx)
+
+			require.NotEmpty(t, id)
+			assert.Equal(t, id, rec.Header().Get(RequestIDHeader))
+			assert.Equal(t, tc.wantSame, id == tc.giveID)
+			assert.Equal(t, []logger.Field{{Key: "request_id", Value: id}}, logger.Fields(ctx))
+		})
+	}
+}
+
+func TestRequestID_logger(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := logger.New(&buf, logger.Info)
+
+	h := RequestID(func(w http.ResponseWriter, r *http.Request) {
+		log.WithContext(r.Context()).Infof("handled")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+
+	h(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "INF: handled request_id=abc\n", buf.String())
+}
+
+func TestWithRequestID(t *testing.T) {
+	var got []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(RequestIDHeader))
+	}))
+	defer srv.Close()
+
+	var auth fakeAuthenticator = func(r *http.Request) error {
+		r.Header.Set("Authorization", "test")
+		return nil
+	}
+
+	client := WithRequestID(WithAuthenticator(srv.Client(), auth))
+
+	req, _ := http.NewRequestWithContext(ContextWithRequestID(context.Background(), "abc"), "GET", srv.URL, nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Empty(t, req.Header.Get(RequestIDHeader))
+
+	req, _ = http.NewRequestWithContext(context.Background(), "GET", srv.URL, nil)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, []string{"abc", ""}, got)
+}
@@ -0,0 +1,233 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RouteOption is a func that adds an optional matching rule to the route.
+type RouteOption func(*route) error
+
+// WithHost restricts the route to requests for the given host. Labels of
+// the form "{name}" capture a host parameter, exposed along with the path
+// parameters, and "*" matches any single label. The host is compared case
+// insensitively and the port is ignored.
+//
+//	mux.AddRoute("GET", "/users", h, WithHost("{tenant}.api.example.com"))
+func WithHost(pattern string) RouteOption {
+	return func(r *route) error {
+		regex, params, err := compileHost(pattern)
+		if err != nil {
+			return fmt.Errorf("host %q: %w", pattern, err)
+		}
+
+		for _, hp := range params {
+			for _, p := range r.params {
+				if p.name == hp.name {
+					return fmt.Errorf("host %q: duplicate parameter %q", pattern, hp.name)
+				}
+			}
+		}
+
+		r.host, r.hostRaw, r.hostParams = regex, pattern, params
+
+		return nil
+	}
+}
+
+// WithHeader restricts the route to requests carrying the given header. An
+// empty value only requires the header to be present, otherwise one of the
+// comma-separated header elements, without its parameters, must be equal to
+// the value. Elements with the "q=0" quality value are not acceptable and do
+// not match. That makes it suitable for media type negotiation.
+//
+//	mux.AddRoute("GET", "/users", h, WithHeader("Accept", "application/vnd.v2+json"))
+func WithHeader(key, value string) RouteOption {
+	return func(r *route) error {
+		if key == "" {
+			return errors.New("empty header key")
+		}
+
+		r.headers = append(r.headers, headerMatcher{
+			key:   textproto.CanonicalMIMEHeaderKey(key),
+			value: value,
+		})
+
+		return nil
+	}
+}
+
+// WithQuery restricts the route to requests with the given query parameter.
+// An empty value only requires the parameter to be present.
+func WithQuery(key, value string) RouteOption {
+	return func(r *route) error {
+		if key == "" {
+			return errors.New("empty query key")
+		}
+
+		r.queries = append(r.queries, queryMatcher{key: key, value: value})
+
+		return nil
+	}
+}
+
+type headerMatcher struct {
+	key   string
+	value string
+}
+
+func (m headerMatcher) match(header http.Header) bool {
+	values := header.Values(m.key)
+	if len(values) == 0 {
+		return false
+	}
+
+	if m.value == "" {
+		return true
+	}
+
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			var params string
+			if i := strings.IndexByte(elem, ';'); i >= 0 {
+				elem, params = elem[:i], elem[i+1:]
+			}
+
+			if strings.EqualFold(strings.TrimSpace(elem), m.value) && !excluded(params) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// excluded reports whether the element parameters have the zero quality
+// value, which marks the element as not acceptable.
+func excluded(params string) bool {
+	for _, p := range strings.Split(params, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+
+		return err == nil && q == 0
+	}
+
+	return false
+}
+
+type queryMatcher struct {
+	key   string
+	value string
+}
+
+func (m queryMatcher) match(query url.Values) bool {
+	values, ok := query[m.key]
+	if !ok {
+		return false
+	}
+
+	if m.value == "" {
+		return true
+	}
+
+	for _, v := range values {
+		if v == m.value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchRequest verifies the host, header and query requirements of the route.
+func (r route) matchRequest(req *http.Request) bool {
+	if r.host != nil && !r.host.MatchString(requestHost(req)) {
+		return false
+	}
+
+	for _, h := range r.headers {
+		if !h.match(req.Header) {
+			return false
+		}
+	}
+
+	if len(r.queries) == 0 {
+		return true
+	}
+
+	query := req.URL.Query()
+
+	for _, q := range r.queries {
+		if !q.match(query) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compileHost compiles the host pattern into an anchored regular expression
+// with a capture group per host parameter.
+func compileHost(pattern string) (*regexp.Regexp, []param, error) {
+	labels := strings.Split(strings.TrimSuffix(pattern, "."), ".")
+	parts := make([]string, 0, len(labels))
+	names := make(map[string]bool)
+
+	var params []param
+
+	for _, label := range labels {
+		switch {
+		case label == "":
+			return nil, nil, errors.New("empty label")
+		case label == "*":
+			parts = append(parts, `[^.]+`)
+		case strings.HasPrefix(label, "{") && strings.HasSuffix(label, "}"):
+			name := label[1 : len(label)-1]
+			if !paramNameRegex.MatchString(name) {
+				return nil, nil, fmt.Errorf("invalid parameter name %q", name)
+			}
+
+			if names[name] {
+				return nil, nil, fmt.Errorf("duplicate parameter %q", name)
+			}
+
+			names[name] = true
+			parts = append(parts, `([^.]+)`)
+			params = append(params, param{name: name, expr: `[^.]+`})
+		default:
+			parts = append(parts, regexp.QuoteMeta(label))
+		}
+	}
+
+	regex, err := regexp.Compile(`(?i)^` + strings.Join(parts, `\.`) + `$`)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return regex, params, nil
+}
+
+// requestHost returns the request host without the port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if host == "" && r.URL != nil {
+		host = r.URL.Host
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(host, ".")
+}
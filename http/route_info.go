@@ -0,0 +1,124 @@
+package http
+
+// RouteDoc is an optional route metadata used for the API documentation.
+type RouteDoc struct {
+	// OperationID is a unique operation identifier.
+	OperationID string
+	// Summary is a short summary of the route.
+	Summary string
+	// Description is a verbose explanation of the route behavior.
+	Description string
+	// Tags are used for logical grouping of routes.
+	Tags []string
+	// Request is a value of the Go type expected in the request body.
+	Request interface{}
+	// Responses are values of the Go types written in the response body,
+	// by status code. A nil value stands for the response without a body.
+	Responses map[int]interface{}
+	// Params describes query and header parameters, or adds details to the
+	// parameters declared in the route pattern.
+	Params []ParamDoc
+}
+
+// ParamDoc describes a single route parameter.
+type ParamDoc struct {
+	// Name is the parameter name.
+	Name string
+	// In is the parameter location: "path", "query" or "header".
+	In string
+	// Description is a parameter description.
+	Description string
+	// Required marks a query or header parameter as mandatory.
+	Required bool
+	// Type is a value of the parameter Go type, defaults to string.
+	Type interface{}
+}
+
+// RouteInfo is a read-only description of the registered route.
+type RouteInfo struct {
+	Method  string
+	Pattern string
+	Host    string
+	Params  []RouteParam
+	// Headers and Queries are the requirements added with WithHeader and
+	// WithQuery.
+	Headers []RouteRequirement
+	Queries []RouteRequirement
+	Doc     *RouteDoc
+}
+
+// RouteRequirement is a header or query parameter the route requires. An
+// empty Value only requires it to be present.
+type RouteRequirement struct {
+	Key   string
+	Value string
+}
+
+// RouteParam is a host or path parameter declared in the route patterns.
+type RouteParam struct {
+	// Name is the parameter name.
+	Name string
+	// Type is the named type of the parameter, if declared.
+	Type string
+	// Pattern is the regular expression the parameter value matches.
+	Pattern string
+	// Optional is true for optional path segments.
+	Optional bool
+	// Wildcard is true for the catch-all path segment.
+	Wildcard bool
+	// Host is true for the parameters captured from the host.
+	Host bool
+}
+
+// WithDoc attaches documentation metadata to the route.
+func WithDoc(doc RouteDoc) RouteOption {
+	return func(r *route) error {
+		r.doc = &doc
+		return nil
+	}
+}
+
+// Routes returns descriptions of the registered routes in the matching order.
+func (m *Mux) Routes() []RouteInfo {
+	infos := make([]RouteInfo, 0, len(m.routes))
+
+	for _, r := range m.routes {
+		info := RouteInfo{
+			Method:  r.method,
+			Pattern: r.pattern,
+			Host:    r.hostRaw,
+			Doc:     r.doc,
+		}
+
+		for _, p := range r.hostParams {
+			info.Params = append(info.Params, routeParam(p, true))
+		}
+
+		for _, p := range r.params {
+			info.Params = append(info.Params, routeParam(p, false))
+		}
+
+		for _, h := range r.headers {
+			info.Headers = append(info.Headers, RouteRequirement{Key: h.key, Value: h.value})
+		}
+
+		for _, q := range r.queries {
+			info.Queries = append(info.Queries, RouteRequirement{Key: q.key, Value: q.value})
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+func routeParam(p param, host bool) RouteParam {
+	return RouteParam{
+		Name:     p.name,
+		Type:     p.typ,
+		Pattern:  p.expr,
+		Optional: p.optional,
+		Wildcard: p.wildcard,
+		Host:     host,
+	}
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := map[string]struct {
+		giveConfig SecurityConfig
+		wantHeader http.Header
+	}{
+		"defaults": {
+			DefaultSecurityConfig(),
+			http.Header{
+				"Strict-Transport-Security": {"max-age=63072000; includeSubDomains"},
+				"Content-Security-Policy":   {"default-src 'none'; frame-ancestors 'none'"},
+				"X-Content-Type-Options":    {"nosniff"},
+				"X-Frame-Options":           {"DENY"},
+				"Referrer-Policy":           {"no-referrer"},
+				"Permissions-Policy":        {"accelerometer=(), camera=(), geolocation=(), microphone=(), payment=(), usb=()"},
+			},
+		},
+		"custom": {
+			SecurityConfig{
+				HSTSMaxAge:     time.Hour,
+				HSTSPreload:    true,
+				FrameOptions:   "SAMEORIGIN",
+				ReferrerPolicy: "strict-origin-when-cross-origin",
+			},
+			http.Header{
+				"Strict-Transport-Security": {"max-age=3600; preload"},
+				"X-Frame-Options":           {"SAMEORIGIN"},
+				"Referrer-Policy":           {"strict-origin-when-cross-origin"},
+			},
+		},
+		"empty": {
+			SecurityConfig{},
+			http.Header{},
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := SecurityHeaders(tc.giveConfig)(func(w http.ResponseWriter, r *http.Request) {})
+
+			rec := httptest.NewRecorder()
+			h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tc.wantHeader, rec.Header())
+		})
+	}
+}
+
+func TestSecurityHeaders_Nonce(t *testing.T) {
+	h := SecurityHeaders(SecurityConfig{
+		ContentSecurityPolicy: "script-src 'nonce-{nonce}'; style-src 'nonce-{nonce}'",
+	})(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, CSPNonce(r.Context()))
+	})
+
+	serve := func() (string, string) {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		return rec.Body.String(), rec.Header().Get("Content-Security-Policy")
+	}
+
+	nonce1, csp1 := serve()
+	nonce2, _ := serve()
+
+	assert.NotEmpty(t, nonce1)
+	assert.NotEqual(t, nonce1, nonce2, "nonce is generated per request")
+	assert.Equal(t, "script-src 'nonce-"+nonce1+"'; style-src 'nonce-"+nonce1+"'", csp1)
+}
@@ -8,13 +8,17 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
 
 // Server is a struct that wraps an http transport listener.
 type Server struct {
-	srv Listener
+	srv      Listener
+	hooks    []func()
+	drain    time.Duration
+	shutdown sync.Once
 }
 
 // Listener is an interface for http server listener.
@@ -28,7 +32,22 @@ func New(srv Listener) *Server {
 	return &Server{srv: srv}
 }
 
-// Serve runs the bootstrapped http server listener.
+// WithShutdownHook adds the funcs called as soon as the shutdown begins,
+// such as failing the readiness check.
+func (s *Server) WithShutdownHook(hooks ...func()) {
+	s.hooks = append(s.hooks, hooks...)
+}
+
+// WithDrainDelay sets the wait between calling the shutdown hooks and closing
+// the listener, so that load balancers stop sending new requests. The wait
+// ends early when the shutdown context is done.
+func (s *Server) WithDrainDelay(d time.Duration) {
+	s.drain = d
+}
+
+// Serve runs the bootstrapped http server listener. On the interrupt or
+// termination signal, or when the listener fails, it shuts the server down
+// with Shutdown.
 func (s *Server) Serve(ctx context.Context) error {
 	errsCh := make(chan error)
 
@@ -47,10 +66,14 @@ func (s *Server) Serve(ctx context.Context) error {
 			return err
 		}
 	case <-shutdownCh:
+		if err := s.Shutdown(ctx); err != nil {
+			return fmt.Errorf("shutting down: %w", err)
+		}
+
 		return errors.New("process terminated")
 	}
 
-	err := s.srv.Shutdown(ctx)
+	err := s.Shutdown(ctx)
 	if err != nil {
 		return fmt.Errorf("shutting down: %w", err)
 	}
@@ -61,3 +84,32 @@ func (s *Server) Serve(ctx context.Context) error {
 
 	return err
 }
+
+// Shutdown calls the shutdown hooks, waits for the drain delay and
+// gracefully shuts down the listener. The hooks and the delay run only once,
+// when Shutdown is called more than once.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdown.Do(func() {
+		s.beginShutdown(ctx)
+	})
+
+	return s.srv.Shutdown(ctx)
+}
+
+func (s *Server) beginShutdown(ctx context.Context) {
+	for _, hook := range s.hooks {
+		hook()
+	}
+
+	if s.drain <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(s.drain)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeListener struct {
+	listenErr error
+	shutdowns int
+}
+
+func (l *fakeListener) ListenAndServe() error {
+	return l.listenErr
+}
+
+func (l *fakeListener) Shutdown(context.Context) error {
+	l.shutdowns++
+	return nil
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	l := &fakeListener{}
+	hooks := 0
+
+	srv := New(l)
+	srv.WithShutdownHook(func() { hooks++ })
+	srv.WithDrainDelay(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, srv.Shutdown(ctx), "drain delay ends with the context")
+	require.NoError(t, srv.Shutdown(context.Background()))
+
+	assert.Equal(t, 1, hooks, "hooks are called once")
+	assert.Equal(t, 2, l.shutdowns)
+}
+
+func TestServer_Serve_ListenerError(t *testing.T) {
+	l := &fakeListener{listenErr: errors.New("address in use")}
+	hooks := 0
+
+	srv := New(l)
+	srv.WithShutdownHook(func() { hooks++ })
+
+	srv.Serve(context.Background()) // nolint:errcheck
+
+	assert.Equal(t, 1, hooks, "hooks are called when the listener fails")
+	assert.Equal(t, 1, l.shutdowns)
+}
@@ -0,0 +1,384 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+	"github.com/diptanw/go-toolkit/internal/random"
+)
+
+// Headers of the signed requests.
+const (
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+	ContentSHA256Header      = "X-Content-SHA256"
+)
+
+const (
+	signatureScheme       = "HMAC-SHA256"
+	defaultSignatureSkew  = 5 * time.Minute
+	defaultSignatureBody  = 1 << 20
+	nonceSweepInterval    = time.Minute
+	signatureNonceByteLen = 16
+)
+
+// requiredSignedHeaders are always signed.
+var requiredSignedHeaders = []string{
+	"host",
+	strings.ToLower(ContentSHA256Header),
+	strings.ToLower(SignatureNonceHeader),
+	strings.ToLower(SignatureTimestampHeader),
+}
+
+// HMACAuthenticator returns the Authenticator that signs requests with
+// HMAC-SHA256 using the secret. The canonical request consists of the method,
+// path, query, the given headers along with host, timestamp, nonce and body
+// digest headers, similar to AWS Signature Version 4. The signature is set
+// to the Authorization header:
+//
+//	Authorization: HMAC-SHA256 KeyId=<id>,SignedHeaders=<h1;h2>,Signature=<hex>
+//
+// Each signature has a fresh nonce, so the client retrying requests must
+// apply WithAuthenticator before retry.WithPolicy to sign every attempt.
+func HMACAuthenticator(keyID string, secret []byte, headers ...string) Authenticator {
+	signed := append([]string{}, requiredSignedHeaders...)
+
+	for _, h := range headers {
+		signed = append(signed, strings.ToLower(h))
+	}
+
+	sort.Strings(signed)
+
+	return hmacAuthenticator{
+		keyID:   keyID,
+		secret:  secret,
+		headers: dedupe(signed),
+		now:     time.Now,
+	}
+}
+
+type hmacAuthenticator struct {
+	keyID   string
+	secret  []byte
+	headers []string
+	now     func() time.Time
+}
+
+func (a hmacAuthenticator) Authenticate(r *http.Request) error {
+	body, err := readBody(r)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	r.Header.Set(SignatureTimestampHeader, strconv.FormatInt(a.now().Unix(), 10))
+	r.Header.Set(SignatureNonceHeader, hex.EncodeToString(random.Bytes(signatureNonceByteLen)))
+	r.Header.Set(ContentSHA256Header, sha256Hex(body))
+
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+
+	sig := signRequest(a.secret, r, host, a.headers)
+
+	r.Header.Set("Authorization", fmt.Sprintf("%s KeyId=%s,SignedHeaders=%s,Signature=%s",
+		signatureScheme, a.keyID, strings.Join(a.headers, ";"), sig))
+
+	return nil
+}
+
+// NonceStore is a pluggable storage of used nonces.
+type NonceStore interface {
+	// Use records the nonce for the given TTL, and reports whether it was
+	// not used before.
+	Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// HMACConfig is a configuration for the signature verification middleware.
+type HMACConfig struct {
+	// Keys are the signing secrets by key ID.
+	Keys map[string][]byte
+	// MaxSkew is the maximum difference between the signature timestamp and
+	// the current time, defaults to 5 minutes.
+	MaxSkew time.Duration
+	// Nonces stores the used nonces, defaults to a new InMemoryNonces.
+	Nonces NonceStore
+	// MaxBodySize is the maximum size of the request body in bytes read to
+	// verify its digest, defaults to 1 MiB.
+	MaxBodySize int64
+}
+
+// VerifyHMAC returns a middleware func that verifies the request signatures
+// produced by HMACAuthenticator. Requests with a timestamp out of the skew
+// window, or with a nonce used before within the window, are rejected to
+// prevent replays. The key ID is put into the request context as the
+// principal. Requests failing the verification are responded with 401
+// Unauthorized, and requests with a body larger than MaxBodySize with 413
+// Request Entity Too Large.
+func VerifyHMAC(cfg HMACConfig) MiddlewareFunc {
+	if cfg.MaxSkew == 0 {
+		cfg.MaxSkew = defaultSignatureSkew
+	}
+
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = defaultSignatureBody
+	}
+
+	if cfg.Nonces == nil {
+		cfg.Nonces = NewInMemoryNonces()
+	}
+
+	v := hmacVerifier{cfg: cfg, now: time.Now}
+
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			keyID, err := v.verify(r)
+
+			switch {
+			case errors.Is(err, errInvalidSignature):
+				jsonapi.WriteError(w, http.StatusUnauthorized, err.Error())
+				return
+			case errors.Is(err, jsonapi.ErrBodyTooLarge):
+				jsonapi.WriteError(w, http.StatusRequestEntityTooLarge)
+				return
+			case err != nil:
+				jsonapi.WriteError(w, http.StatusInternalServerError)
+				return
+			}
+
+			h.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), keyID)))
+		}
+	}
+}
+
+var errInvalidSignature = errors.New("invalid signature")
+
+type hmacVerifier struct {
+	cfg HMACConfig
+	now func() time.Time
+}
+
+func (v hmacVerifier) verify(r *http.Request) (string, error) {
+	params, ok := parseSignatureAuth(r.Header.Get("Authorization"))
+	if !ok {
+		return "", fmt.Errorf("%w: missing or malformed authorization", errInvalidSignature)
+	}
+
+	secret, ok := v.cfg.Keys[params["KeyId"]]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown key", errInvalidSignature)
+	}
+
+	headers := strings.Split(params["SignedHeaders"], ";")
+	for _, h := range requiredSignedHeaders {
+		if !contains(headers, h) {
+			return "", fmt.Errorf("%w: %s header is not signed", errInvalidSignature, h)
+		}
+	}
+
+	ts, err := strconv.ParseInt(r.Header.Get(SignatureTimestampHeader), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid timestamp", errInvalidSignature)
+	}
+
+	if skew := v.now().Sub(time.Unix(ts, 0)); skew > v.cfg.MaxSkew || skew < -v.cfg.MaxSkew {
+		return "", fmt.Errorf("%w: timestamp is out of window", errInvalidSignature)
+	}
+
+	if r.Header.Get(SignatureNonceHeader) == "" {
+		return "", fmt.Errorf("%w: missing nonce", errInvalidSignature)
+	}
+
+	if r.ContentLength > v.cfg.MaxBodySize {
+		return "", jsonapi.ErrBodyTooLarge
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = &maxBytesReader{ReadCloser: r.Body, n: v.cfg.MaxBodySize}
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return "", err
+	}
+
+	if !hmac.Equal([]byte(sha256Hex(body)), []byte(r.Header.Get(ContentSHA256Header))) {
+		return "", fmt.Errorf("%w: body digest mismatch", errInvalidSignature)
+	}
+
+	sig := signRequest(secret, r, r.Host, headers)
+	if !hmac.Equal([]byte(sig), []byte(params["Signature"])) {
+		return "", errInvalidSignature
+	}
+
+	// The nonce is recorded only for the authentic requests, and kept for
+	// the whole window the timestamp is accepted in.
+	fresh, err := v.cfg.Nonces.Use(r.Context(), r.Header.Get(SignatureNonceHeader), 2*v.cfg.MaxSkew)
+	if err != nil {
+		return "", err
+	}
+
+	if !fresh {
+		return "", fmt.Errorf("%w: replayed request", errInvalidSignature)
+	}
+
+	return params["KeyId"], nil
+}
+
+// signRequest returns the hex encoded signature of the canonical request.
+func signRequest(secret []byte, r *http.Request, host string, headers []string) string {
+	var b strings.Builder
+
+	b.WriteString(r.Method + "\n")
+	b.WriteString(r.URL.EscapedPath() + "\n")
+	b.WriteString(r.URL.Query().Encode() + "\n")
+
+	for _, h := range headers {
+		v := host
+		if h != "host" {
+			v = strings.Join(r.Header.Values(h), ",")
+		}
+
+		b.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+
+	b.WriteString("\n" + strings.Join(headers, ";") + "\n")
+	b.WriteString(r.Header.Get(ContentSHA256Header))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signatureScheme + "\n" + sha256Hex([]byte(b.String()))))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseSignatureAuth parses the comma separated key=value parameters of the
+// HMAC-SHA256 authorization header.
+func parseSignatureAuth(auth string) (map[string]string, bool) {
+	if !strings.HasPrefix(auth, signatureScheme+" ") {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+
+	for _, p := range strings.Split(auth[len(signatureScheme)+1:], ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok {
+			return nil, false
+		}
+
+		params[k] = v
+	}
+
+	for _, k := range []string{"KeyId", "SignedHeaders", "Signature"} {
+		if params[k] == "" {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// readBody reads the request body and replaces it with a copy.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	return body, nil
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func dedupe(sorted []string) []string {
+	res := sorted[:0]
+
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
+
+// InMemoryNonces is the in-memory NonceStore, expired nonces are evicted
+// periodically.
+type InMemoryNonces struct {
+	nonces    map[string]time.Time
+	lastSweep time.Time
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+// NewInMemoryNonces returns a new instance of InMemoryNonces.
+func NewInMemoryNonces() *InMemoryNonces {
+	return &InMemoryNonces{
+		nonces: make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Use records the nonce and reports whether it was not used before.
+func (s *InMemoryNonces) Use(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	if now.Sub(s.lastSweep) > nonceSweepInterval {
+		s.lastSweep = now
+
+		for n, exp := range s.nonces {
+			if now.After(exp) {
+				delete(s.nonces, n)
+			}
+		}
+	}
+
+	if exp, ok := s.nonces[nonce]; ok && !now.After(exp) {
+		return false, nil
+	}
+
+	s.nonces[nonce] = now.Add(ttl)
+
+	return true, nil
+}
@@ -0,0 +1,199 @@
+package http
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/diptanw/go-toolkit/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyHMAC(t *testing.T) {
+	now := time.Now()
+	secret := []byte("secret")
+
+	signed := func(t *testing.T, secret []byte, signedAt time.Time, body string) *http.Request {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodPost, "http://api.example.com/hooks?b=2&a=1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		auth := HMACAuthenticator("k1", secret, "Content-Type").(hmacAuthenticator)
+		auth.now = func() time.Time { return signedAt }
+
+		require.NoError(t, auth.Authenticate(req))
+
+		return req
+	}
+
+	tests := map[string]struct {
+		giveReq  func(t *testing.T) *http.Request
+		wantCode int
+		wantBody string
+	}{
+		"valid": {
+			func(t *testing.T) *http.Request {
+				return signed(t, secret, now, `{"a":1}`)
+			},
+			http.StatusOK,
+			`k1:{"a":1}`,
+		},
+		"missing signature": {
+			func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/hooks", nil)
+			},
+			http.StatusUnauthorized,
+			`{"errors":["invalid signature: missing or malformed authorization"]}`,
+		},
+		"wrong secret": {
+			func(t *testing.T) *http.Request {
+				return signed(t, []byte("other"), now, `{"a":1}`)
+			},
+			http.StatusUnauthorized,
+			`{"errors":["invalid signature"]}`,
+		},
+		"tampered body": {
+			func(t *testing.T) *http.Request {
+				req := signed(t, secret, now, `{"a":1}`)
+				req.Body = ioutil.NopCloser(strings.NewReader(`{"a":2}`))
+
+				return req
+			},
+			http.StatusUnauthorized,
+			`{"errors":["invalid signature: body digest mismatch"]}`,
+		},
+		"tampered header": {
+			func(t *testing.T) *http.Request {
+				req := signed(t, secret, now, `{"a":1}`)
+				req.Header.Set("Content-Type", "text/plain")
+
+				return req
+			},
+			http.StatusUnauthorized,
+			`{"errors":["invalid signature"]}`,
+		},
+		"tampered query": {
+			func(t *testing.T) *http.Request {
+				req := signed(t, secret, now, `{"a":1}`)
+				req.URL.RawQuery = "a=1&b=3"
+
+				return req
+			},
+			http.StatusUnauthorized,
+			`{"errors":["invalid signature"]}`,
+		},
+		"expired": {
+			func(t *testing.T) *http.Request {
+				return signed(t, secret, now.Add(-10*time.Minute), `{"a":1}`)
+			},
+			http.StatusUnauthorized,
+			`{"errors":["invalid signature: timestamp is out of window"]}`,
+		},
+		"body too large": {
+			func(t *testing.T) *http.Request {
+				return signed(t, secret, now, strings.Repeat("a", defaultSignatureBody+1))
+			},
+			http.StatusRequestEntityTooLarge,
+			`{"errors":["Request Entity Too Large"]}`,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mw := VerifyHMAC(HMACConfig{Keys: map[string][]byte{"k1": secret}})
+			h := mw(func(w http.ResponseWriter, r *http.Request) {
+				p, _ := PrincipalFromContext(r.Context())
+				body, _ := ioutil.ReadAll(r.Body)
+				io.WriteString(w, p+":"+string(body))
+			})
+
+			rec := httptest.NewRecorder()
+			h(rec, tc.giveReq(t))
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+		})
+	}
+}
+
+func TestVerifyHMAC_Replay(t *testing.T) {
+	srv := httptest.NewServer(VerifyHMAC(HMACConfig{
+		Keys: map[string][]byte{"k1": []byte("secret")},
+	})(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client := WithAuthenticator(srv.Client(), HMACAuthenticator("k1", []byte("secret")))
+
+	resp, err := client.Post(srv.URL+"/hooks", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The captured request is replayed with the same signature and nonce.
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/hooks", strings.NewReader(`{}`))
+	require.NoError(t, err)
+
+	req.Header = resp.Request.Header.Clone()
+
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestVerifyHMAC_Retry(t *testing.T) {
+	var attempts int32
+
+	srv := httptest.NewServer(VerifyHMAC(HMACConfig{
+		Keys: map[string][]byte{"k1": []byte("secret")},
+	})(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	client := WithAuthenticator(srv.Client(), HMACAuthenticator("k1", []byte("secret")))
+	client = retry.WithPolicy(client, retry.Policy{
+		WaitMin:  time.Millisecond,
+		WaitMax:  time.Millisecond,
+		RetryMax: 2,
+	})
+
+	resp, err := client.Post(srv.URL+"/hooks", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "every attempt is signed with a fresh nonce")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestInMemoryNonces_Use(t *testing.T) {
+	now := time.Now()
+	s := NewInMemoryNonces()
+	s.now = func() time.Time { return now }
+
+	fresh, err := s.Use(context.Background(), "n1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, _ = s.Use(context.Background(), "n1", time.Minute)
+	assert.False(t, fresh)
+
+	now = now.Add(2 * time.Minute)
+
+	fresh, _ = s.Use(context.Background(), "n1", time.Minute)
+	assert.True(t, fresh, "expired nonce can be reused")
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIndex = "index.html"
+	gzipExt      = ".gz"
+)
+
+// StaticConfig is a configuration for the static files handler.
+type StaticConfig struct {
+	// CachePolicies are the Cache-Control values for the files matching the
+	// glob pattern. The first matching policy is applied.
+	CachePolicies []CachePolicy
+	// Index is the file served for the directory requests, defaults to
+	// "index.html".
+	Index string
+	// SPA enables the single page application mode, when the root index file
+	// is served for the paths without extension that are not found.
+	SPA bool
+}
+
+// CachePolicy is the Cache-Control value for the files matching the pattern.
+// Patterns without a slash are matched against the file base name, otherwise
+// against the path relative to the root, using path.Match syntax.
+type CachePolicy struct {
+	Pattern      string
+	CacheControl string
+}
+
+// Static returns a handler serving files from the given file system, such as
+// embed.FS or os.DirFS. Responses carry a strong ETag and Last-Modified
+// validators and support conditional and range requests. A precompressed
+// ".gz" variant of the file is served to the clients accepting gzip encoding.
+// When mounted on Mux with a catch-all parameter, its value is used as the
+// file path, otherwise the request URL path is used.
+//
+//	mux.AddRoute("GET", "/assets/*path", http.Static(assets, http.StaticConfig{}))
+func Static(fsys fs.FS, cfg StaticConfig) http.HandlerFunc {
+	if cfg.Index == "" {
+		cfg.Index = defaultIndex
+	}
+
+	h := &staticHandler{fsys: fsys, cfg: cfg}
+
+	return h.serve
+}
+
+type staticHandler struct {
+	fsys  fs.FS
+	cfg   StaticConfig
+	etags sync.Map
+}
+
+type etagKey struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (h *staticHandler) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	name, ok := wildcard(r.Context())
+	if !ok {
+		name = r.URL.Path
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+
+	err := h.serveFile(w, r, name, "")
+
+	if errors.Is(err, fs.ErrNotExist) && h.cfg.SPA && path.Ext(name) == "" {
+		err = h.serveFile(w, r, h.cfg.Index, "no-cache")
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func (h *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name, cacheControl string) error {
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		name = path.Join(name, h.cfg.Index)
+	}
+
+	f, info, err := h.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+
+	// Prefer the precompressed variant if it exists and the client accepts it.
+	if gz, gzInfo, err := h.open(name + gzipExt); err == nil {
+		defer gz.Close()
+
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if acceptsEncoding(r, "gzip") {
+			f, info = gz, gzInfo
+			name += gzipExt
+
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		return fmt.Errorf("file %s is not seekable", name)
+	}
+
+	etag, err := h.etag(name, info, content)
+	if err != nil {
+		return err
+	}
+
+	if cacheControl == "" {
+		cacheControl = h.cacheControl(name)
+	}
+
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("ETag", etag)
+
+	http.ServeContent(w, r, name, info.ModTime(), content)
+
+	return nil
+}
+
+func (h *staticHandler) open(name string) (fs.File, fs.FileInfo, error) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	if info.IsDir() {
+		f.Close()
+		return nil, nil, fs.ErrNotExist
+	}
+
+	return f, info, nil
+}
+
+// etag returns the strong entity tag of the file content. Tags are cached
+// until the file size or modification time changes.
+func (h *staticHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	key := etagKey{name: name, size: info.Size(), modTime: info.ModTime()}
+
+	if etag, ok := h.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+
+	hash := sha256.New()
+
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	h.etags.Store(key, etag)
+
+	return etag, nil
+}
+
+func (h *staticHandler) cacheControl(name string) string {
+	name = strings.TrimSuffix(name, gzipExt)
+
+	for _, p := range h.cfg.CachePolicies {
+		target := name
+		if !strings.Contains(p.Pattern, "/") {
+			target = path.Base(name)
+		}
+
+		if ok, _ := path.Match(p.Pattern, target); ok {
+			return p.CacheControl
+		}
+	}
+
+	return ""
+}
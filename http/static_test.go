@@ -0,0 +1,241 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStaticFS() fstest.MapFS {
+	modTime := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	return fstest.MapFS{
+		"index.html":         {Data: []byte("<html>index</html>"), ModTime: modTime},
+		"assets/app.js":      {Data: []byte("console.log('app')"), ModTime: modTime},
+		"assets/app.js.gz":   {Data: []byte("gzipped"), ModTime: modTime},
+		"assets/style.css":   {Data: []byte("body{}"), ModTime: modTime},
+		"docs/index.html":    {Data: []byte("<html>docs</html>"), ModTime: modTime},
+		"assets/empty/.keep": {Data: []byte{}},
+	}
+}
+
+func TestStatic(t *testing.T) {
+	tests := map[string]struct {
+		giveConfig   StaticConfig
+		givePath     string
+		giveHeader   http.Header
+		wantCode     int
+		wantBody     string
+		wantHeader   http.Header
+		wantNoHeader []string
+	}{
+		"file": {
+			StaticConfig{},
+			"/assets/style.css",
+			nil,
+			http.StatusOK,
+			"body{}",
+			http.Header{"Last-Modified": {"Sun, 01 May 2022 00:00:00 GMT"}},
+			[]string{"Cache-Control", "Content-Encoding"},
+		},
+		"directory index": {
+			StaticConfig{},
+			"/docs/",
+			nil,
+			http.StatusOK,
+			"<html>docs</html>",
+			nil,
+			nil,
+		},
+		"directory without index": {
+			StaticConfig{},
+			"/assets/empty",
+			nil,
+			http.StatusNotFound,
+			"404 page not found\n",
+			nil,
+			nil,
+		},
+		"precompressed variant": {
+			StaticConfig{},
+			"/assets/app.js",
+			http.Header{"Accept-Encoding": {"br, gzip"}},
+			http.StatusOK,
+			"gzipped",
+			http.Header{
+				"Content-Encoding": {"gzip"},
+				"Vary":             {"Accept-Encoding"},
+			},
+			nil,
+		},
+		"precompressed variant not accepted": {
+			StaticConfig{},
+			"/assets/app.js",
+			http.Header{"Accept-Encoding": {"gzip;q=0"}},
+			http.StatusOK,
+			"console.log('app')",
+			http.Header{"Vary": {"Accept-Encoding"}},
+			[]string{"Content-Encoding"},
+		},
+		"cache policy by base name": {
+			StaticConfig{CachePolicies: []CachePolicy{
+				{Pattern: "*.css", CacheControl: "public, max-age=31536000, immutable"},
+			}},
+			"/assets/style.css",
+			nil,
+			http.StatusOK,
+			"body{}",
+			http.Header{"Cache-Control": {"public, max-age=31536000, immutable"}},
+			nil,
+		},
+		"cache policy by path": {
+			StaticConfig{CachePolicies: []CachePolicy{
+				{Pattern: "docs/*", CacheControl: "no-store"},
+				{Pattern: "*.html", CacheControl: "no-cache"},
+			}},
+			"/docs/index.html",
+			nil,
+			http.StatusOK,
+			"<html>docs</html>",
+			http.Header{"Cache-Control": {"no-store"}},
+			nil,
+		},
+		"range request": {
+			StaticConfig{},
+			"/assets/style.css",
+			http.Header{"Range": {"bytes=0-3"}},
+			http.StatusPartialContent,
+			"body",
+			http.Header{"Content-Range": {"bytes 0-3/6"}},
+			nil,
+		},
+		"not modified since": {
+			StaticConfig{},
+			"/assets/style.css",
+			http.Header{"If-Modified-Since": {"Sun, 01 May 2022 00:00:00 GMT"}},
+			http.StatusNotModified,
+			"",
+			nil,
+			nil,
+		},
+		"not found": {
+			StaticConfig{},
+			"/missing",
+			nil,
+			http.StatusNotFound,
+			"404 page not found\n",
+			nil,
+			nil,
+		},
+		"spa fallback": {
+			StaticConfig{SPA: true},
+			"/users/1",
+			nil,
+			http.StatusOK,
+			"<html>index</html>",
+			http.Header{"Cache-Control": {"no-cache"}},
+			nil,
+		},
+		"spa fallback skips files": {
+			StaticConfig{SPA: true},
+			"/missing.js",
+			nil,
+			http.StatusNotFound,
+			"404 page not found\n",
+			nil,
+			nil,
+		},
+		"path traversal": {
+			StaticConfig{},
+			"/../index.html",
+			nil,
+			http.StatusOK,
+			"<html>index</html>",
+			nil,
+			nil,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			h := Static(newStaticFS(), tc.giveConfig)
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.URL.Path = tc.givePath
+
+			for k, v := range tc.giveHeader {
+				req.Header[k] = v
+			}
+
+			rec := httptest.NewRecorder()
+			h(rec, req)
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+
+			for k, v := range tc.wantHeader {
+				assert.Equal(t, v, rec.Header().Values(k), k)
+			}
+
+			for _, k := range tc.wantNoHeader {
+				assert.Empty(t, rec.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestStatic_etag(t *testing.T) {
+	h := Static(newStaticFS(), StaticConfig{})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/assets/style.css", nil))
+
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.NotContains(t, etag, "W/")
+
+	req := httptest.NewRequest("GET", "/assets/style.css", nil)
+	req.Header.Set("If-None-Match", etag)
+
+	rec = httptest.NewRecorder()
+	h(rec, req)
+
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestStatic_contentType(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/assets/app.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	Static(newStaticFS(), StaticConfig{})(rec, req)
+
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+}
+
+func TestStatic_mux(t *testing.T) {
+	m := Mux{}
+	m.AddRoute("GET", "/static/*path", Static(newStaticFS(), StaticConfig{}))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/static/assets/style.css", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "body{}", rec.Body.String())
+}
+
+func TestStatic_methodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Static(newStaticFS(), StaticConfig{})(rec, httptest.NewRequest("POST", "/index.html", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
@@ -0,0 +1,214 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+)
+
+// Timeout returns a middleware func that runs the handler with the request
+// context cancelled after the given duration. The response is buffered, and if
+// the handler does not return in time, 503 Service Unavailable JSON error is
+// written and flushed instead and further handler writes fail with
+// http.ErrHandlerTimeout. The middleware returns once the handler does, so
+// that panics in the handler, even after the deadline, are propagated to the
+// caller goroutine along with the stack of the handler goroutine.
+func Timeout(d time.Duration) MiddlewareFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			r = r.WithContext(ctx)
+			tw := &timeoutWriter{ctx: ctx, header: make(http.Header)}
+			done := make(chan struct{})
+			panicCh := make(chan interface{}, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicCh <- handlerPanic(p)
+					}
+				}()
+
+				h.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicCh:
+				panic(p)
+			case <-done:
+				// The handler may return after the deadline with its late
+				// writes rejected, so the buffered response is incomplete.
+				if ctx.Err() == nil {
+					tw.mu.Lock()
+					defer tw.mu.Unlock()
+
+					header := w.Header()
+					for k, v := range tw.header {
+						header[k] = v
+					}
+
+					if tw.status == 0 {
+						tw.status = http.StatusOK
+					}
+
+					w.WriteHeader(tw.status)
+					w.Write(tw.buf.Bytes())
+
+					return
+				}
+			case <-ctx.Done():
+			}
+
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				jsonapi.WriteError(w, http.StatusServiceUnavailable, "request timed out")
+			}
+
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+
+			select {
+			case p := <-panicCh:
+				panic(p)
+			case <-done:
+			}
+		}
+	}
+}
+
+// TimeoutPanic is the value the Timeout middleware re-panics with when the
+// handler panics, as the handler runs in its own goroutine.
+type TimeoutPanic struct {
+	// Value is the value the handler panicked with.
+	Value interface{}
+	// Stack is the stack trace of the handler goroutine.
+	Stack []byte
+}
+
+// String returns the panic value followed by the handler stack trace.
+func (p TimeoutPanic) String() string {
+	return fmt.Sprintf("%v\n\n%s", p.Value, p.Stack)
+}
+
+// handlerPanic returns the TimeoutPanic with the current stack. The
+// http.ErrAbortHandler sentinel is kept as is, so that the server aborts the
+// response silently.
+func handlerPanic(p interface{}) interface{} {
+	if p == http.ErrAbortHandler {
+		return p
+	}
+
+	return TimeoutPanic{Value: p, Stack: debug.Stack()}
+}
+
+// timeoutWriter buffers the response of the handler until it returns.
+type timeoutWriter struct {
+	ctx    context.Context
+	header http.Header
+	buf    bytes.Buffer
+	status int
+	mu     sync.Mutex
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.expired() || w.status != 0 {
+		return
+	}
+
+	w.status = code
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.buf.Write(b)
+}
+
+// expired reports whether the handler writes are late. The context is checked
+// rather than a flag set by the middleware, as the handler may observe the
+// context done first.
+func (w *timeoutWriter) expired() bool {
+	return w.ctx.Err() != nil
+}
+
+// MaxBodySize returns a middleware func that limits the size of the request
+// body to n bytes. Requests with larger Content-Length are responded with 413
+// Request Entity Too Large, and reading past the limit of a body of unknown
+// length fails with jsonapi.ErrBodyTooLarge.
+func MaxBodySize(n int64) MiddlewareFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				jsonapi.WriteError(w, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				r = r.Clone(r.Context())
+				r.Body = &maxBytesReader{ReadCloser: r.Body, n: n}
+			}
+
+			h.ServeHTTP(w, r)
+		}
+	}
+}
+
+// maxBytesReader fails with jsonapi.ErrBodyTooLarge when more than n bytes
+// are read.
+type maxBytesReader struct {
+	io.ReadCloser
+	n   int64
+	err error
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+
+	if int64(n) <= r.n {
+		r.n -= int64(n)
+		r.err = err
+
+		return n, err
+	}
+
+	n = int(r.n)
+	r.n = 0
+	r.err = jsonapi.ErrBodyTooLarge
+
+	return n, r.err
+}
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := map[string]struct {
+		giveHandler http.HandlerFunc
+		wantCode    int
+		wantBody    string
+		wantHeader  string
+	}{
+		"in time": {
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Test", "yes")
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, "ok")
+			},
+			http.StatusCreated,
+			"ok",
+			"yes",
+		},
+		"deadline exceeded": {
+			func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+				w.Header().Set("X-Test", "yes")
+			},
+			http.StatusServiceUnavailable,
+			`{"errors":["request timed out"]}`,
+			"",
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			Timeout(50*time.Millisecond)(tc.giveHandler)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+			assert.Equal(t, tc.wantHeader, rec.Header().Get("X-Test"))
+		})
+	}
+}
+
+func TestTimeout_WriteAfterDeadline(t *testing.T) {
+	errCh := make(chan error, 1)
+
+	h := Timeout(10 * time.Millisecond)(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+
+		_, err := io.WriteString(w, "late")
+		errCh <- err
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.ErrorIs(t, <-errCh, http.ErrHandlerTimeout)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.True(t, rec.Flushed)
+}
+
+func TestTimeout_Panic(t *testing.T) {
+	tests := map[string]struct {
+		giveHandler http.HandlerFunc
+		wantCode    int
+	}{
+		"before deadline": {
+			func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+			http.StatusOK,
+		},
+		"after deadline": {
+			func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+				panic("boom")
+			},
+			http.StatusServiceUnavailable,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+
+			p := func() (p interface{}) {
+				defer func() { p = recover() }()
+
+				Timeout(10*time.Millisecond)(tc.giveHandler)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+				return nil
+			}()
+
+			require.IsType(t, TimeoutPanic{}, p)
+			assert.Equal(t, "boom", p.(TimeoutPanic).Value)
+			assert.Contains(t, string(p.(TimeoutPanic).Stack), "timeout_test.go")
+			assert.Equal(t, tc.wantCode, rec.Code)
+		})
+	}
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		Timeout(time.Second)(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestMaxBodySize(t *testing.T) {
+	tests := map[string]struct {
+		giveBody   string
+		giveLength int64
+		wantCode   int
+		wantBody   string
+	}{
+		"within limit": {
+			`{"a":1}`,
+			7,
+			http.StatusOK,
+			`{"a":1}`,
+		},
+		"content length exceeded": {
+			`{"a":12345}`,
+			11,
+			http.StatusRequestEntityTooLarge,
+			`{"errors":["Request Entity Too Large"]}`,
+		},
+		"unknown length exceeded": {
+			`{"a":12345}`,
+			-1,
+			http.StatusRequestEntityTooLarge,
+			`{"errors":["request body too large"]}`,
+		},
+		"invalid json": {
+			`{"a":`,
+			-1,
+			http.StatusBadRequest,
+			`{"errors":["unexpected end of JSON input"]}`,
+		},
+	}
+
+	h := MaxBodySize(8)(func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]int
+		if err := jsonapi.Read(r, &v); err != nil {
+			jsonapi.WriteError(w, jsonapi.StatusCode(err), err.Error())
+			return
+		}
+
+		jsonapi.Write(w, v)
+	})
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.giveBody))
+			req.ContentLength = tc.giveLength
+
+			rec := httptest.NewRecorder()
+			h(rec, req)
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+		})
+	}
+}
+
+func TestRead_MaxBytes(t *testing.T) {
+	tests := map[string]struct {
+		giveBody string
+		wantErr  error
+	}{
+		"within limit": {`{"a":1}`, nil},
+		"exact limit":  {`{"a":12}`, nil},
+		"exceeded":     {`{"a":123}`, jsonapi.ErrBodyTooLarge},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.giveBody))
+			req.ContentLength = -1
+
+			var v map[string]int
+
+			err := jsonapi.Read(req, &v, jsonapi.WithMaxBytes(8))
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
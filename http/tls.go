@@ -0,0 +1,289 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/diptanw/go-toolkit/http/jsonapi"
+)
+
+const defReloadInterval = time.Minute
+
+// TLSConfig is a configuration for the TLS listener.
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM encoded certificate chain and private
+	// key files, which are reloaded when they change.
+	CertFile string
+	KeyFile  string
+	// ClientCAs is the pool of CAs verifying the client certificates, which
+	// enables mutual TLS.
+	ClientCAs *x509.CertPool
+	// ClientAuth is the client certificate policy. Zero value requires and
+	// verifies the certificate when ClientCAs is set.
+	ClientAuth tls.ClientAuthType
+	// MinVersion is the minimum TLS version. Zero value keeps the one of the
+	// server TLS config, or defaults to TLS 1.2 if it is not set either.
+	MinVersion uint16
+	// CipherSuites is a list of the enabled TLS 1.0-1.2 cipher suites. Zero
+	// value keeps the ones of the server TLS config, or the Go defaults.
+	CipherSuites []uint16
+	// ReloadInterval is the period of checking the certificate files for
+	// changes. Zero value defaults to 1m. The certificate is also reloaded
+	// on SIGHUP.
+	ReloadInterval time.Duration
+	// OnReloadError is called with the errors of the background reload, the
+	// previous certificate is served until the next successful one.
+	OnReloadError func(error)
+}
+
+// TLSListener is the Listener serving HTTPS with the certificate reloaded
+// from files without restarting.
+type TLSListener struct {
+	srv      *http.Server
+	certs    *certReloader
+	interval time.Duration
+	onError  func(error)
+	done     chan struct{}
+	close    sync.Once
+}
+
+// NewTLSListener returns a new instance of TLSListener for the server. The
+// server TLS config is replaced with a copy, whose settings are overridden by
+// the ones set in cfg. It returns an error if the certificate cannot be
+// loaded.
+func NewTLSListener(srv *http.Server, cfg TLSConfig) (*TLSListener, error) {
+	certs := &certReloader{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+	if err := certs.reload(true); err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{}
+	if srv.TLSConfig != nil {
+		tlsCfg = srv.TLSConfig.Clone()
+	}
+
+	tlsCfg.GetCertificate = certs.getCertificate
+	tlsCfg.Certificates = nil
+
+	if cfg.MinVersion != 0 {
+		tlsCfg.MinVersion = cfg.MinVersion
+	}
+
+	if tlsCfg.MinVersion == 0 {
+		tlsCfg.MinVersion = tls.VersionTLS12
+	}
+
+	if len(cfg.CipherSuites) > 0 {
+		tlsCfg.CipherSuites = cfg.CipherSuites
+	}
+
+	if cfg.ClientCAs != nil {
+		tlsCfg.ClientCAs = cfg.ClientCAs
+		tlsCfg.ClientAuth = cfg.ClientAuth
+
+		if tlsCfg.ClientAuth == tls.NoClientCert {
+			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	srv.TLSConfig = tlsCfg
+
+	interval := cfg.ReloadInterval
+	if interval <= 0 {
+		interval = defReloadInterval
+	}
+
+	return &TLSListener{
+		srv:      srv,
+		certs:    certs,
+		interval: interval,
+		onError:  cfg.OnReloadError,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// ListenAndServe listens on the server address and serves HTTPS.
+func (l *TLSListener) ListenAndServe() error {
+	go l.watch()
+	return l.srv.ListenAndServeTLS("", "")
+}
+
+// Serve serves HTTPS on the given listener.
+func (l *TLSListener) Serve(ln net.Listener) error {
+	go l.watch()
+	return l.srv.ServeTLS(ln, "", "")
+}
+
+// Shutdown stops reloading the certificate and gracefully shuts down the
+// server.
+func (l *TLSListener) Shutdown(ctx context.Context) error {
+	l.close.Do(func() {
+		close(l.done)
+	})
+
+	return l.srv.Shutdown(ctx)
+}
+
+// Reload loads the certificate files, as on SIGHUP.
+func (l *TLSListener) Reload() error {
+	return l.certs.reload(true)
+}
+
+func (l *TLSListener) watch() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		var force bool
+
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		case <-hup:
+			force = true
+		}
+
+		if err := l.certs.reload(force); err != nil && l.onError != nil {
+			l.onError(err)
+		}
+	}
+}
+
+// certReloader holds the certificate loaded from files.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+	mu       sync.RWMutex
+}
+
+// reload loads the certificate files if they have changed since the last
+// successful load, or unconditionally if forced.
+func (c *certReloader) reload(force bool) error {
+	modTimes, err := c.stat()
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	unchanged := c.cert != nil && modTimes == c.modTimes
+	c.mu.RUnlock()
+
+	if unchanged && !force {
+		return nil
+	}
+
+	// A mismatch of the files being replaced fails, and is loaded again on
+	// the next attempt.
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTimes = modTimes
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *certReloader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+
+	for i, name := range []string{c.certFile, c.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return modTimes, fmt.Errorf("load certificate: %w", err)
+		}
+
+		modTimes[i] = fi.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cert, nil
+}
+
+// LoadCertPool returns the pool of certificates from the PEM encoded files,
+// such as the CAs verifying the client certificates.
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	for _, name := range files {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("load cert pool: %w", err)
+		}
+
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("load cert pool: no certificates in %s", name)
+		}
+	}
+
+	return pool, nil
+}
+
+// ClientCertificate returns the verified client certificate of the request,
+// and false if the client did not present a certificate verified by the
+// configured CAs.
+func ClientCertificate(r *http.Request) (*x509.Certificate, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	return r.TLS.VerifiedChains[0][0], true
+}
+
+// RequireClientCert is a middleware func that authenticates requests with
+// the verified client certificate. The first URI SAN of the certificate, such
+// as a SPIFFE ID, is put into the request context as the principal, or the
+// first DNS SAN if there is no URI. The subject common name, deprecated for
+// identities by RFC 6125, is used only for the certificates without either.
+// Requests without the verified certificate are responded with 401
+// Unauthorized.
+func RequireClientCert(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cert, ok := ClientCertificate(r)
+		if !ok {
+			jsonapi.WriteError(w, http.StatusUnauthorized, "client certificate required")
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), certPrincipal(cert))))
+	}
+}
+
+// certPrincipal returns the identity of the certificate.
+func certPrincipal(cert *x509.Certificate) string {
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	default:
+		return cert.Subject.CommonName
+	}
+}
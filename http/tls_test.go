@@ -0,0 +1,326 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue returns the PEM encoded certificate and key signed by the CA.
+func (ca testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeCert writes the server certificate files with the given modification
+// time.
+func (ca testCA) writeCert(t *testing.T, dir, cn string, mod time.Time) (string, string) {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, cn, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	require.NoError(t, ioutil.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, ioutil.WriteFile(keyFile, keyPEM, 0o600))
+	require.NoError(t, os.Chtimes(certFile, mod, mod))
+	require.NoError(t, os.Chtimes(keyFile, mod, mod))
+
+	return certFile, keyFile
+}
+
+func TestTLSListener(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.writeCert(t, dir, "server-1", time.Now().Add(-time.Minute))
+
+	srv := &http.Server{Handler: RequireClientCert(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		io.WriteString(w, principal)
+	})}
+
+	l, err := NewTLSListener(srv, TLSConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ClientCAs:      ca.pool,
+		ReloadInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, uint16(tls.VersionTLS12), srv.TLSConfig.MinVersion)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, srv.TLSConfig.ClientAuth)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go l.Serve(ln) // nolint:errcheck
+
+	t.Cleanup(func() {
+		l.Shutdown(context.Background()) // nolint:errcheck
+	})
+
+	clientCert, clientKey := ca.issue(t, "client-1", x509.ExtKeyUsageClientAuth)
+	pair, err := tls.X509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{RootCAs: ca.pool, Certificates: certs},
+		}}
+	}
+
+	url := "https://" + ln.Addr().String()
+
+	resp, err := newClient(pair).Get(url)
+	require.NoError(t, err)
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, "client-1", string(body), "client identity is the principal")
+	assert.Equal(t, "server-1", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	_, err = newClient().Get(url)
+	assert.Error(t, err, "client certificate is required")
+
+	ca.writeCert(t, dir, "server-2", time.Now())
+
+	assert.Eventually(t, func() bool {
+		resp, err := newClient(pair).Get(url)
+		if err != nil {
+			return false
+		}
+
+		resp.Body.Close()
+
+		return resp.TLS.PeerCertificates[0].Subject.CommonName == "server-2"
+	}, time.Second, 10*time.Millisecond, "changed certificate is reloaded")
+}
+
+func TestTLSListener_Reload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	mod := time.Now().Add(-time.Minute)
+	certFile, keyFile := ca.writeCert(t, dir, "server-1", mod)
+
+	l, err := NewTLSListener(&http.Server{}, TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+
+	// The files replaced with the same modification time are loaded on an
+	// explicit reload only.
+	ca.writeCert(t, dir, "server-2", mod)
+
+	require.NoError(t, l.certs.reload(false))
+	assert.Equal(t, "server-1", commonName(t, l))
+
+	require.NoError(t, l.Reload())
+	assert.Equal(t, "server-2", commonName(t, l))
+
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("invalid"), 0o600))
+	assert.Error(t, l.Reload())
+	assert.Equal(t, "server-2", commonName(t, l), "previous certificate is served on error")
+
+	_, err = NewTLSListener(&http.Server{}, TLSConfig{CertFile: "missing.crt", KeyFile: "missing.key"})
+	assert.Error(t, err)
+}
+
+func TestLoadCertPool(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0o600))
+
+	invalidFile := filepath.Join(dir, "invalid.crt")
+	require.NoError(t, ioutil.WriteFile(invalidFile, []byte("invalid"), 0o600))
+
+	pool, err := LoadCertPool(caFile)
+	require.NoError(t, err)
+	_, err = ca.cert.Verify(x509.VerifyOptions{Roots: pool})
+	assert.NoError(t, err)
+
+	_, err = LoadCertPool(invalidFile)
+	assert.EqualError(t, err, "load cert pool: no certificates in "+invalidFile)
+
+	_, err = LoadCertPool(filepath.Join(dir, "missing.crt"))
+	assert.Error(t, err)
+}
+
+func TestNewTLSListener_ServerConfig(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.writeCert(t, t.TempDir(), "server-1", time.Now())
+	suites := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+
+	tests := map[string]struct {
+		giveServer     *tls.Config
+		giveCfg        TLSConfig
+		wantMinVersion uint16
+		wantSuites     []uint16
+	}{
+		"defaults": {
+			nil,
+			TLSConfig{},
+			tls.VersionTLS12,
+			nil,
+		},
+		"server config is kept": {
+			&tls.Config{MinVersion: tls.VersionTLS13, CipherSuites: suites},
+			TLSConfig{},
+			tls.VersionTLS13,
+			suites,
+		},
+		"config overrides server": {
+			&tls.Config{MinVersion: tls.VersionTLS13},
+			TLSConfig{MinVersion: tls.VersionTLS12, CipherSuites: suites},
+			tls.VersionTLS12,
+			suites,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.giveCfg.CertFile, tc.giveCfg.KeyFile = certFile, keyFile
+			srv := &http.Server{TLSConfig: tc.giveServer}
+
+			_, err := NewTLSListener(srv, tc.giveCfg)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.wantMinVersion, srv.TLSConfig.MinVersion)
+			assert.Equal(t, tc.wantSuites, srv.TLSConfig.CipherSuites)
+		})
+	}
+}
+
+func TestCertPrincipal(t *testing.T) {
+	spiffe, err := url.Parse("spiffe://example.org/billing")
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		giveCert *x509.Certificate
+		want     string
+	}{
+		"uri san": {
+			&x509.Certificate{URIs: []*url.URL{spiffe}, DNSNames: []string{"billing.local"}},
+			"spiffe://example.org/billing",
+		},
+		"dns san": {
+			&x509.Certificate{DNSNames: []string{"billing.local"}, Subject: pkix.Name{CommonName: "billing"}},
+			"billing.local",
+		},
+		"common name": {
+			&x509.Certificate{Subject: pkix.Name{CommonName: "billing"}},
+			"billing",
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, certPrincipal(tc.giveCert))
+		})
+	}
+}
+
+func TestRequireClientCert_NoTLS(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	RequireClientCert(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler is not called")
+	})(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.JSONEq(t, `{"errors":["client certificate required"]}`, rec.Body.String())
+}
+
+func commonName(t *testing.T, l *TLSListener) string {
+	t.Helper()
+
+	cert, err := l.certs.getCertificate(nil)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
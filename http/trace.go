@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/diptanw/go-toolkit/trace"
+)
+
+// Trace returns a middleware func that continues the trace of the incoming
+// request from the traceparent and tracestate headers, or starts a new one,
+// with the server span named after the method and route pattern.
+func Trace(tracer *trace.Tracer) MiddlewareFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			route := RoutePattern(r.Context())
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			ctx := trace.Extract(r.Context(), trace.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method+" "+route,
+				trace.WithKind(trace.KindServer),
+				trace.WithAttribute("http.method", r.Method),
+				trace.WithAttribute("http.route", route),
+				trace.WithAttribute("http.target", r.URL.Path))
+
+			defer span.End()
+
+			ww, rec := wrapWriter(w)
+
+			h.ServeHTTP(ww, r.WithContext(ctx))
+
+			span.SetAttribute("http.status_code", rec.Status())
+
+			if rec.Status() >= http.StatusInternalServerError {
+				span.SetStatus(trace.StatusError, http.StatusText(rec.Status()))
+			}
+		}
+	}
+}
+
+// WithTracing returns a copy of http.Client with the transport starting a
+// client span for every request, and propagating it in the traceparent and
+// tracestate headers.
+func WithTracing(client *http.Client, tracer *trace.Tracer) *http.Client {
+	cp := *client
+	if cp.Transport == nil {
+		cp.Transport = http.DefaultTransport
+	}
+
+	cp.Transport = traceTransport{
+		tracer: tracer,
+		next:   cp.Transport,
+	}
+
+	return &cp
+}
+
+type traceTransport struct {
+	tracer *trace.Tracer
+	next   http.RoundTripper
+}
+
+func (t traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithKind(trace.KindClient),
+		trace.WithAttribute("http.method", req.Method),
+		trace.WithAttribute("http.url", req.URL.Redacted()))
+
+	defer span.End()
+
+	// RoundTripper should not modify the request.
+	req = req.Clone(ctx)
+	trace.Inject(ctx, trace.HeaderCarrier(req.Header))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+
+	span.SetAttribute("http.status_code", resp.StatusCode)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(trace.StatusError, http.StatusText(resp.StatusCode))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diptanw/go-toolkit/trace"
+)
+
+type spanRecorder struct {
+	spans []trace.SpanData
+	mu    sync.Mutex
+}
+
+func (r *spanRecorder) Export(_ context.Context, spans []trace.SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = append(r.spans, spans...)
+
+	return nil
+}
+
+func TestTrace(t *testing.T) {
+	rec := &spanRecorder{}
+	tracer := trace.NewTracer(trace.Config{Exporter: rec})
+
+	var downstream http.Header
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(backend.Close)
+
+	client := WithTracing(backend.Client(), tracer)
+
+	mux := &Mux{}
+	mux.WithMiddleware(Trace(tracer))
+	mux.AddRoute(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, backend.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=1")
+
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.NoError(t, tracer.Close(context.Background()))
+	require.Len(t, rec.spans, 2)
+
+	clientSpan, server := rec.spans[0], rec.spans[1]
+
+	assert.Equal(t, "GET /users/:id", server.Name)
+	assert.Equal(t, trace.KindServer, server.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.Context.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.String())
+	assert.Equal(t, 500, server.Attributes["http.status_code"])
+	assert.Equal(t, "/users/1", server.Attributes["http.target"])
+	assert.Equal(t, trace.StatusError, server.Status)
+
+	assert.Equal(t, "HTTP GET", clientSpan.Name)
+	assert.Equal(t, trace.KindClient, clientSpan.Kind)
+	assert.Equal(t, server.Context.SpanID, clientSpan.Parent)
+	assert.Equal(t, 502, clientSpan.Attributes["http.status_code"])
+
+	assert.Equal(t, trace.FormatTraceParent(clientSpan.Context), downstream.Get("traceparent"))
+	assert.Equal(t, "vendor=1", downstream.Get("tracestate"))
+}
+
+func TestTrace_newTrace(t *testing.T) {
+	rec := &spanRecorder{}
+	tracer := trace.NewTracer(trace.Config{Exporter: rec})
+
+	mux := &Mux{}
+	mux.WithMiddleware(Trace(tracer))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("traceparent", "invalid")
+
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.NoError(t, tracer.Close(context.Background()))
+	require.Len(t, rec.spans, 1)
+
+	assert.Equal(t, "GET unmatched", rec.spans[0].Name)
+	assert.False(t, rec.spans[0].Parent.IsValid())
+	assert.True(t, rec.spans[0].Context.IsValid())
+}
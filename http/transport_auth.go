@@ -11,7 +11,10 @@ type Authenticator interface {
 }
 
 // WithAuthenticator returns a copy of http.Client with authentication transport.
-// Should be set in the foremost end of the round-tripper chain.
+// The request is cloned before it is authenticated, so each round trip is
+// authenticated afresh. It should be the innermost transport of the chain,
+// applied before retry.WithPolicy, so that every retry attempt gets its own
+// credentials, as a signature with a nonce cannot be sent twice.
 func WithAuthenticator(client *http.Client, auth Authenticator) *http.Client {
 	cp := *client
 	if cp.Transport == nil {
@@ -32,6 +35,9 @@ type transport struct {
 }
 
 func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper should not modify the request.
+	req = req.Clone(req.Context())
+
 	if err := t.auth.Authenticate(req); err != nil {
 		return nil, fmt.Errorf("authenticate request: %w", err)
 	}
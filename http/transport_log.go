@@ -0,0 +1,351 @@
+package http
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+const redacted = "[REDACTED]"
+
+// ClientLogConfig is a configuration for the client logging transport.
+type ClientLogConfig struct {
+	// MaxBodySize is the number of request and response body bytes included
+	// in the log lines. Zero value does not capture bodies.
+	MaxBodySize int
+	// RedactHeaders is a list of additional header names whose values are
+	// not logged. Authorization, Proxy-Authorization, Cookie and Set-Cookie
+	// are always redacted.
+	RedactHeaders []string
+	// RedactQuery is a list of additional query parameter names whose values
+	// are not logged, such as the API keys set with InQuery. The access_token,
+	// api_key, key and token parameters are always redacted.
+	RedactQuery []string
+}
+
+// WithLogging returns a copy of http.Client with a transport that logs the
+// outbound requests and responses with the Debug level, along with the
+// timing breakdown of DNS lookup, connection, TLS handshake and time to the
+// first response byte. Nothing is captured when the Debug level is disabled.
+// Bodies are captured as they are read, so the response is logged when its
+// body is read to the end or closed, and the request with a body that cannot
+// be read again, when it is sent.
+// To log every attempt with the final headers, it should be set before the
+// authentication and retry transports.
+func WithLogging(client *http.Client, log logger.Logger, cfg ClientLogConfig) *http.Client {
+	cp := *client
+	if cp.Transport == nil {
+		cp.Transport = http.DefaultTransport
+	}
+
+	redact := map[string]bool{
+		"Authorization":       true,
+		"Proxy-Authorization": true,
+		"Cookie":              true,
+		"Set-Cookie":          true,
+	}
+
+	for _, h := range cfg.RedactHeaders {
+		redact[http.CanonicalHeaderKey(h)] = true
+	}
+
+	redactQuery := map[string]bool{
+		"access_token": true,
+		"api_key":      true,
+		"key":          true,
+		"token":        true,
+	}
+
+	for _, q := range cfg.RedactQuery {
+		redactQuery[q] = true
+	}
+
+	cp.Transport = logTransport{
+		log:         log,
+		maxBody:     cfg.MaxBodySize,
+		redact:      redact,
+		redactQuery: redactQuery,
+		next:        cp.Transport,
+	}
+
+	return &cp
+}
+
+type logTransport struct {
+	log         logger.Logger
+	maxBody     int
+	redact      map[string]bool
+	redactQuery map[string]bool
+	next        http.RoundTripper
+}
+
+func (t logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.log.Enabled(logger.Debug) {
+		return t.next.RoundTrip(req)
+	}
+
+	log := t.log.WithContext(req.Context()).
+		With("method", req.Method).
+		With("url", t.url(req.URL))
+
+	timing := &clientTiming{start: time.Now()}
+	out := req.WithContext(httptrace.WithClientTrace(req.Context(), timing.trace()))
+
+	reqLog := log.With("header", t.header(req.Header))
+
+	// The body that cannot be read again is captured while it is sent.
+	var sent *captureBody
+
+	if t.maxBody > 0 && out.Body != nil && out.Body != http.NoBody {
+		if out.GetBody != nil {
+			reqLog = reqLog.With("body", t.requestBody(out))
+		} else {
+			sent = &captureBody{ReadCloser: out.Body, max: t.maxBody}
+			out.Body = sent
+		}
+	}
+
+	if sent == nil {
+		reqLog.Debugf("http client request")
+	}
+
+	resp, err := t.next.RoundTrip(out)
+
+	if sent != nil {
+		if body := sent.captured(); len(body) > 0 {
+			reqLog = reqLog.With("body", t.truncate(body))
+		}
+
+		reqLog.Debugf("http client request")
+	}
+
+	log = timing.keyValue(log)
+
+	if err != nil {
+		log.With("error", err).Debugf("http client request failed")
+		return nil, err
+	}
+
+	respLog := log.
+		With("status", resp.StatusCode).
+		With("header", t.header(resp.Header))
+
+	if t.maxBody == 0 || resp.Body == nil || resp.Body == http.NoBody {
+		respLog.Debugf("http client response")
+		return resp, nil
+	}
+
+	body := &captureBody{ReadCloser: resp.Body, max: t.maxBody}
+	body.done = func() {
+		if b := body.captured(); len(b) > 0 {
+			respLog = respLog.With("body", t.truncate(b))
+		}
+
+		respLog.Debugf("http client response")
+	}
+
+	resp.Body = body
+
+	return resp, nil
+}
+
+// requestBody returns the captured body of the request read from a copy.
+func (t logTransport) requestBody(req *http.Request) string {
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+
+	defer body.Close()
+
+	prefix, _ := ioutil.ReadAll(io.LimitReader(body, int64(t.maxBody)+1))
+
+	return t.truncate(prefix)
+}
+
+// url returns the URL without the password and the values of the redacted
+// query parameters.
+func (t logTransport) url(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+
+	params := strings.Split(u.RawQuery, "&")
+
+	for i, p := range params {
+		k, _, _ := strings.Cut(p, "=")
+
+		name, err := url.QueryUnescape(k)
+		if err != nil || t.redactQuery[name] {
+			params[i] = k + "=" + redacted
+		}
+	}
+
+	cp := *u
+	cp.RawQuery = strings.Join(params, "&")
+
+	return cp.Redacted()
+}
+
+func (t logTransport) truncate(b []byte) string {
+	if len(b) > t.maxBody {
+		return string(b[:t.maxBody]) + "..."
+	}
+
+	return string(b)
+}
+
+func (t logTransport) header(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		v := strings.Join(h[k], ", ")
+		if t.redact[http.CanonicalHeaderKey(k)] {
+			v = redacted
+		}
+
+		lines = append(lines, k+": "+v)
+	}
+
+	return strings.Join(lines, "; ")
+}
+
+// captureBody keeps the first bytes of the body as it is read, up to one
+// byte over the max, and calls done once the body is read to the end or
+// closed.
+type captureBody struct {
+	io.ReadCloser
+
+	max  int
+	buf  bytes.Buffer
+	done func()
+	once sync.Once
+	mu   sync.Mutex
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.mu.Lock()
+	if rest := b.max + 1 - b.buf.Len(); rest > 0 {
+		if rest > n {
+			rest = n
+		}
+
+		b.buf.Write(p[:rest])
+	}
+	b.mu.Unlock()
+
+	if err == io.EOF {
+		b.finish()
+	}
+
+	return n, err
+}
+
+func (b *captureBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+
+	return err
+}
+
+func (b *captureBody) captured() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func (b *captureBody) finish() {
+	if b.done != nil {
+		b.once.Do(b.done)
+	}
+}
+
+// clientTiming collects the request timings reported by httptrace. The hooks
+// may be called concurrently, for example when dialing multiple addresses.
+type clientTiming struct {
+	start    time.Time
+	dns      time.Duration
+	connect  time.Duration
+	tls      time.Duration
+	ttfb     time.Duration
+	reused   bool
+	dnsStart time.Time
+	dialFrom time.Time
+	tlsStart time.Time
+	mu       sync.Mutex
+}
+
+func (c *clientTiming) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			c.set(func() { c.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			c.set(func() { c.dns = time.Since(c.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			c.set(func() {
+				if c.dialFrom.IsZero() {
+					c.dialFrom = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				c.set(func() { c.connect = time.Since(c.dialFrom) })
+			}
+		},
+		TLSHandshakeStart: func() {
+			c.set(func() { c.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			c.set(func() { c.tls = time.Since(c.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.set(func() { c.reused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			c.set(func() { c.ttfb = time.Since(c.start) })
+		},
+	}
+}
+
+func (c *clientTiming) set(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn()
+}
+
+func (c *clientTiming) keyValue(log logger.Logger) logger.Logger {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return log.
+		With("dns", c.dns).
+		With("connect", c.connect).
+		With("tls", c.tls).
+		With("ttfb", c.ttfb).
+		With("duration", time.Since(c.start)).
+		With("reused", c.reused)
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+func TestWithLogging(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		w.Header().Set("X-Echo", "yes")
+		w.Write(bytes.ToUpper(b))
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := map[string]struct {
+		giveBody    func() io.Reader
+		giveMaxBody int
+		wantLogs    []string
+		wantMissing []string
+	}{
+		"no body capture": {
+			giveBody: func() io.Reader { return strings.NewReader("hello world") },
+			wantLogs: []string{
+				`DBG: http client request method=POST url=` + srv.URL,
+				`Authorization: [REDACTED]`,
+				`X-Api-Key: [REDACTED]`,
+				`X-Trace: abc`,
+				`DBG: http client response method=POST`,
+				`Set-Cookie: [REDACTED]`,
+				`X-Echo: yes`,
+				`status=200`,
+				`ttfb=`,
+			},
+			wantMissing: []string{"secret", "key-1", "token", "body="},
+		},
+		"replayable body": {
+			giveBody:    func() io.Reader { return strings.NewReader("hello world") },
+			giveMaxBody: 5,
+			wantLogs:    []string{`body=hello...`, `body=HELLO...`},
+		},
+		"streamed body": {
+			giveBody:    func() io.Reader { return io.MultiReader(strings.NewReader("hello")) },
+			giveMaxBody: 64,
+			wantLogs:    []string{`body=hello`, `body=HELLO`},
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			client := WithLogging(srv.Client(), logger.New(&buf, logger.Debug), ClientLogConfig{
+				MaxBodySize:   tc.giveMaxBody,
+				RedactHeaders: []string{"x-api-key"},
+			})
+
+			req, err := http.NewRequest(http.MethodPost, srv.URL, tc.giveBody())
+			require.NoError(t, err)
+
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("X-Api-Key", "key-1")
+			req.Header.Set("X-Trace", "abc")
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, strings.ToUpper(readAll(t, tc.giveBody())), string(body), "bodies are passed intact")
+
+			for _, want := range tc.wantLogs {
+				assert.Contains(t, buf.String(), want)
+			}
+
+			for _, missing := range tc.wantMissing {
+				assert.NotContains(t, buf.String(), missing)
+			}
+		})
+	}
+}
+
+func TestWithLogging_Disabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(srv.Close)
+
+	var buf bytes.Buffer
+
+	client := WithLogging(srv.Client(), logger.New(&buf, logger.Info), ClientLogConfig{MaxBodySize: 10})
+
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Empty(t, buf.String())
+}
+
+func TestWithLogging_RedactQuery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+
+	var buf bytes.Buffer
+
+	client := WithLogging(srv.Client(), logger.New(&buf, logger.Debug), ClientLogConfig{
+		RedactQuery: []string{"sig"},
+	})
+
+	resp, err := client.Get(srv.URL + "/items?api_key=key-1&page=2&sig=sig-1")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Contains(t, buf.String(), srv.URL+"/items?api_key=[REDACTED]&page=2&sig=[REDACTED]")
+	assert.NotContains(t, buf.String(), "key-1")
+	assert.NotContains(t, buf.String(), "sig-1")
+}
+
+func TestWithLogging_Error(t *testing.T) {
+	var buf bytes.Buffer
+
+	client := WithLogging(&http.Client{}, logger.New(&buf, logger.Debug), ClientLogConfig{})
+
+	_, err := client.Get("http://127.0.0.1:0")
+	require.Error(t, err)
+
+	assert.Contains(t, buf.String(), "DBG: http client request failed")
+	assert.Contains(t, buf.String(), "error=")
+}
+
+func readAll(t *testing.T, r io.Reader) string {
+	t.Helper()
+
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(b)
+}
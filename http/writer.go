@@ -0,0 +1,137 @@
+package http
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// responseWriter is a http.ResponseWriter wrapper that records the response
+// status code and the number of body bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// wrapWriter wraps the given http.ResponseWriter with the recorder. Returned
+// writer implements http.Flusher and http.Hijacker only if the wrapped one
+// does, so that handlers can still rely on type assertions.
+func wrapWriter(w http.ResponseWriter) (http.ResponseWriter, *responseWriter) {
+	rw := &responseWriter{ResponseWriter: w}
+
+	return extend(rw, w), rw
+}
+
+// extendableWriter is a http.ResponseWriter wrapper that is able to flush and
+// hijack the connection of the wrapped writer.
+type extendableWriter interface {
+	http.ResponseWriter
+	Unwrap() http.ResponseWriter
+	flush()
+	hijack() (net.Conn, *bufio.ReadWriter, error)
+}
+
+// extend returns the writer that exposes http.Flusher and http.Hijacker
+// interfaces if the original writer implements them.
+func extend(w extendableWriter, orig http.ResponseWriter) http.ResponseWriter {
+	_, flusher := orig.(http.Flusher)
+	_, hijacker := orig.(http.Hijacker)
+
+	switch {
+	case flusher && hijacker:
+		return flushHijackWriter{w}
+	case flusher:
+		return flushWriter{w}
+	case hijacker:
+		return hijackWriter{w}
+	default:
+		return w
+	}
+}
+
+// WriteHeader records and sends the response header with the status code.
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the number of bytes written to the response body.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+
+	return n, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the response status code, which is 200 OK if the header was
+// not written explicitly.
+func (w *responseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+func (w *responseWriter) flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && !w.wroteHeader {
+		// The connection is taken over, status is switching protocols.
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+
+	return conn, rw, err
+}
+
+type flushWriter struct {
+	extendableWriter
+}
+
+func (w flushWriter) Flush() {
+	w.flush()
+}
+
+type hijackWriter struct {
+	extendableWriter
+}
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+type flushHijackWriter struct {
+	extendableWriter
+}
+
+func (w flushHijackWriter) Flush() {
+	w.flush()
+}
+
+func (w flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
@@ -0,0 +1,19 @@
+// Package random provides the random tokens and identifiers shared by the
+// toolkit packages.
+package random
+
+import "crypto/rand"
+
+// Read fills b with cryptographically secure random bytes.
+func Read(b []byte) {
+	// The crypto/rand reader never fails on supported platforms.
+	rand.Read(b) // nolint:errcheck
+}
+
+// Bytes returns n cryptographically secure random bytes.
+func Bytes(n int) []byte {
+	b := make([]byte, n)
+	Read(b)
+
+	return b
+}
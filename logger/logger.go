@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Level is the logging level type.
@@ -20,8 +22,17 @@ const (
 type Logger struct {
 	writer io.Writer
 	level  Level
+	fields []Field
 }
 
+// Field is a key-value pair appended to the log lines.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+type contextKey struct{}
+
 // New return a new Logger instance configured with a given logging Level and
 // writer.
 func New(writer io.Writer, level Level) Logger {
@@ -31,6 +42,42 @@ func New(writer io.Writer, level Level) Logger {
 	}
 }
 
+// With returns a copy of Logger that appends the given key-value pair to
+// every log line.
+func (l Logger) With(key string, value interface{}) Logger {
+	l.fields = appendField(l.fields, Field{Key: key, Value: value})
+	return l
+}
+
+// WithContext returns a copy of Logger that appends the fields carried by the
+// given context to every log line.
+func (l Logger) WithContext(ctx context.Context) Logger {
+	for _, f := range Fields(ctx) {
+		l.fields = appendField(l.fields, f)
+	}
+
+	return l
+}
+
+// ContextWith returns a copy of ctx carrying the given key-value pair, which
+// is picked up by Logger.WithContext. It is useful for request scoped values,
+// such as request or trace identifiers.
+func ContextWith(ctx context.Context, key string, value interface{}) context.Context {
+	return context.WithValue(ctx, contextKey{}, appendField(Fields(ctx), Field{Key: key, Value: value}))
+}
+
+// Fields returns the fields carried by the context.
+func Fields(ctx context.Context) []Field {
+	fields, _ := ctx.Value(contextKey{}).([]Field)
+	return fields
+}
+
+// Enabled reports whether the given logging Level is written, to skip
+// building expensive log lines.
+func (l Logger) Enabled(level Level) bool {
+	return level <= l.level
+}
+
 // Infof writes to the output with a Info logging Level.
 func (l Logger) Infof(format string, vals ...interface{}) {
 	l.write(Info, format, vals)
@@ -65,14 +112,42 @@ func (l Logger) prefix(level Level) (string, bool) {
 		prefix = "DBG: "
 	}
 
-	return prefix, level <= l.level
+	return prefix, l.Enabled(level)
 }
 
 func (l Logger) write(level Level, format string, a []interface{}) {
 	if prefix, ok := l.prefix(level); ok {
-		_, err := fmt.Fprintln(l.writer, fmt.Sprintf(prefix+format, a...))
+		_, err := fmt.Fprintln(l.writer, fmt.Sprintf(prefix+format, a...)+l.suffix())
 		if err != nil {
 			return
 		}
 	}
 }
+
+func (l Logger) suffix() string {
+	if len(l.fields) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+
+	for _, f := range l.fields {
+		v := fmt.Sprint(f.Value)
+		if v == "" || strings.ContainsAny(v, " =\"") {
+			v = fmt.Sprintf("%q", v)
+		}
+
+		b.WriteString(" " + f.Key + "=" + v)
+	}
+
+	return b.String()
+}
+
+// appendField returns a new slice with the field appended, so that copies of
+// Logger never share the underlying array.
+func appendField(fields []Field, f Field) []Field {
+	cp := make([]Field, len(fields), len(fields)+1)
+	copy(cp, fields)
+
+	return append(cp, f)
+}
@@ -0,0 +1,19 @@
+package logger
+
+import (
+	"context"
+	"os"
+)
+
+func ExampleLogger_WithContext() {
+	log := New(os.Stdout, Debug).With("service", "users")
+
+	ctx := ContextWith(context.Background(), "request_id", "abc")
+
+	log.WithContext(ctx).Infof("user %d created", 1)
+	log.Warnf("no context")
+
+	// Output:
+	// INF: user 1 created service=users request_id=abc
+	// WRN: no context service=users
+}
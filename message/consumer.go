@@ -11,6 +11,9 @@ type Message struct {
 	ID string
 	// Data is the actual event data received or to be sent.
 	Data []byte
+	// Metadata is the key-value pairs sent along with the data, such as the
+	// trace context.
+	Metadata map[string]string
 
 	// Passing false indicates processing failed.
 	// A non-nil return indicates that the peer has not accepted
@@ -0,0 +1,19 @@
+// Package metrics provides counters, gauges and histograms with labels, and
+// exposes them in the Prometheus text format without depending on the
+// Prometheus client library.
+//
+// Basic Usage
+//
+//	reg := metrics.NewRegistry()
+//
+//	jobs := reg.Counter("jobs_total", "Number of processed jobs.", "queue", "result")
+//	latency := reg.Histogram("job_duration_seconds", "Job duration.", metrics.DefBuckets, "queue")
+//
+//	start := time.Now()
+//	err := process(job)
+//
+//	latency.With("emails").Observe(time.Since(start).Seconds())
+//	jobs.With("emails", result(err)).Inc()
+//
+//	mux.AddRoute("GET", "/metrics", reg.Handler())
+package metrics
@@ -0,0 +1,252 @@
+package metrics
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default histogram buckets for durations in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	nameRegex  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// labelSep joins label values into the series key.
+const labelSep = "\xff"
+
+type metricType string
+
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+// family is a metric with all its labeled series.
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64
+	series  map[string]interface{}
+	mu      sync.RWMutex
+}
+
+// get returns the series for the label values, creating it with newFn.
+func (f *family) get(values []string, newFn func() interface{}) interface{} {
+	if len(values) != len(f.labels) {
+		panic("metrics: " + f.name + " expects labels " + strings.Join(f.labels, ", "))
+	}
+
+	key := strings.Join(values, labelSep)
+
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+
+	if ok {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+
+	s = newFn()
+	f.series[key] = s
+
+	return s
+}
+
+// keys returns the series keys in the sorted order.
+func (f *family) keys() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (f *family) load(key string) interface{} {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.series[key]
+}
+
+// CounterVec is a counter partitioned by label values.
+type CounterVec struct {
+	f *family
+}
+
+// With returns the counter for the label values, given in the order of the
+// label names. With panics if the number of values does not match.
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.f.get(values, func() interface{} { return &Counter{} }).(*Counter)
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	v value
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add increases the counter by the given non-negative value.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+
+	c.v.add(v)
+}
+
+// Value returns the current value.
+func (c *Counter) Value() float64 {
+	return c.v.load()
+}
+
+// GaugeVec is a gauge partitioned by label values.
+type GaugeVec struct {
+	f *family
+}
+
+// With returns the gauge for the label values, given in the order of the
+// label names. With panics if the number of values does not match.
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.f.get(values, func() interface{} { return &Gauge{} }).(*Gauge)
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	v value
+}
+
+// Set sets the gauge to the given value.
+func (g *Gauge) Set(v float64) {
+	g.v.store(v)
+}
+
+// Inc increments the gauge by 1.
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+// Dec decrements the gauge by 1.
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+// Add adds the given value to the gauge.
+func (g *Gauge) Add(v float64) {
+	g.v.add(v)
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 {
+	return g.v.load()
+}
+
+// HistogramVec is a histogram partitioned by label values.
+type HistogramVec struct {
+	f *family
+}
+
+// With returns the histogram for the label values, given in the order of the
+// label names. With panics if the number of values does not match.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.f.get(values, func() interface{} {
+		return &Histogram{
+			upper:  v.f.buckets,
+			counts: make([]uint64, len(v.f.buckets)),
+		}
+	}).(*Histogram)
+}
+
+// Histogram counts observations in the configurable buckets.
+type Histogram struct {
+	// The atomically accessed fields go first for 64-bit alignment.
+	count  uint64
+	sum    value
+	upper  []float64
+	counts []uint64
+}
+
+// Observe adds a single observation.
+func (h *Histogram) Observe(v float64) {
+	// The count is incremented first, so that it is never less than the
+	// buckets read after it.
+	atomic.AddUint64(&h.count, 1)
+	h.sum.add(v)
+
+	if i := sort.SearchFloat64s(h.upper, v); i < len(h.counts) {
+		atomic.AddUint64(&h.counts[i], 1)
+	}
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&h.count)
+}
+
+// Sum returns the sum of observations.
+func (h *Histogram) Sum() float64 {
+	return h.sum.load()
+}
+
+// cumulative returns the cumulative counts of the buckets.
+func (h *Histogram) cumulative() []uint64 {
+	res := make([]uint64, len(h.counts))
+
+	var total uint64
+
+	for i := range h.counts {
+		total += atomic.LoadUint64(&h.counts[i])
+		res[i] = total
+	}
+
+	return res
+}
+
+// value is a float64 updated atomically.
+type value struct {
+	bits uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&v.bits)
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+
+		if atomic.CompareAndSwapUint64(&v.bits, old, updated) {
+			return
+		}
+	}
+}
+
+func (v *value) store(f float64) {
+	atomic.StoreUint64(&v.bits, math.Float64bits(f))
+}
+
+func (v *value) load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&v.bits))
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TextContentType is the content type of the Prometheus text format.
+const TextContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds the metrics and writes them in the Prometheus text format.
+type Registry struct {
+	families map[string]*family
+	mu       sync.RWMutex
+}
+
+// NewRegistry returns a new instance of Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+	}
+}
+
+// Counter registers a new counter with the given label names. Counter panics
+// if the name or labels are invalid, or the name is already registered.
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{f: r.register(name, help, counterType, labels, nil)}
+}
+
+// Gauge registers a new gauge with the given label names. Gauge panics if the
+// name or labels are invalid, or the name is already registered.
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{f: r.register(name, help, gaugeType, labels, nil)}
+}
+
+// Histogram registers a new histogram with the given upper bounds of the
+// buckets and label names. Histogram panics if the name or labels are
+// invalid, the name is already registered, or the buckets are not sorted.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: buckets of " + name + " are not sorted")
+	}
+
+	for _, l := range labels {
+		if l == "le" {
+			panic("metrics: label le is reserved for histograms")
+		}
+	}
+
+	return &HistogramVec{f: r.register(name, help, histogramType, labels, buckets)}
+}
+
+func (r *Registry) register(name, help string, typ metricType, labels []string, buckets []float64) *family {
+	if !nameRegex.MatchString(name) {
+		panic("metrics: invalid metric name " + name)
+	}
+
+	for _, l := range labels {
+		if !labelRegex.MatchString(l) || strings.HasPrefix(l, "__") {
+			panic("metrics: invalid label name " + l)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.families[name]; ok {
+		panic("metrics: duplicate metric " + name)
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]interface{}),
+	}
+
+	r.families[name] = f
+
+	return f
+}
+
+// WriteText writes all the metrics in the Prometheus text format, sorted by
+// the metric name and label values.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+
+	r.mu.RUnlock()
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	bw := bufio.NewWriter(w)
+
+	for _, f := range families {
+		writeFamily(bw, f)
+	}
+
+	return bw.Flush()
+}
+
+// Handler returns the handler func that exposes the metrics in the Prometheus
+// text format.
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", TextContentType)
+		r.WriteText(w) // nolint:errcheck
+	}
+}
+
+func writeFamily(w *bufio.Writer, f *family) {
+	w.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	w.WriteString("# TYPE " + f.name + " " + string(f.typ) + "\n")
+
+	for _, key := range f.keys() {
+		var values []string
+		if len(f.labels) > 0 {
+			values = strings.Split(key, labelSep)
+		}
+
+		labels := formatLabels(f.labels, values)
+
+		switch s := f.load(key).(type) {
+		case *Counter:
+			writeSample(w, f.name, labels, formatFloat(s.Value()))
+		case *Gauge:
+			writeSample(w, f.name, labels, formatFloat(s.Value()))
+		case *Histogram:
+			leNames := append(append([]string{}, f.labels...), "le")
+			cumulative := s.cumulative()
+
+			// Buckets are loaded before the count, so that the +Inf bucket is
+			// not less than others when observations happen concurrently.
+			count := s.Count()
+			sum := s.Sum()
+
+			for i, c := range cumulative {
+				le := append(append([]string{}, values...), formatFloat(s.upper[i]))
+				writeSample(w, f.name+"_bucket", formatLabels(leNames, le), strconv.FormatUint(c, 10))
+			}
+
+			inf := append(append([]string{}, values...), "+Inf")
+			writeSample(w, f.name+"_bucket", formatLabels(leNames, inf), strconv.FormatUint(count, 10))
+			writeSample(w, f.name+"_sum", labels, formatFloat(sum))
+			writeSample(w, f.name+"_count", labels, strconv.FormatUint(count, 10))
+		}
+	}
+}
+
+func writeSample(w *bufio.Writer, name, labels, value string) {
+	w.WriteString(name + labels + " " + value + "\n")
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, n := range names {
+		pairs[i] = n + `="` + escapeLabel(values[i]) + `"`
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	reg := NewRegistry()
+
+	requests := reg.Counter("requests_total", "Number of requests.", "method", "path")
+	requests.With("GET", "/b").Inc()
+	requests.With("GET", "/a").Add(2)
+	requests.With("POST", `/"quoted"`).Inc()
+
+	inFlight := reg.Gauge("in_flight", "Requests in flight.\nMultiline.")
+	inFlight.With().Inc()
+	inFlight.With().Inc()
+	inFlight.With().Dec()
+
+	duration := reg.Histogram("duration_seconds", "Duration.", []float64{0.1, 1}, "method")
+	duration.With("GET").Observe(0.05)
+	duration.With("GET").Observe(0.1)
+	duration.With("GET").Observe(0.5)
+	duration.With("GET").Observe(3)
+
+	var b strings.Builder
+	require.NoError(t, reg.WriteText(&b))
+
+	want := `# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{method="GET",le="0.1"} 2
+duration_seconds_bucket{method="GET",le="1"} 3
+duration_seconds_bucket{method="GET",le="+Inf"} 4
+duration_seconds_sum{method="GET"} 3.65
+duration_seconds_count{method="GET"} 4
+# HELP in_flight Requests in flight.\nMultiline.
+# TYPE in_flight gauge
+in_flight 1
+# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{method="GET",path="/a"} 2
+requests_total{method="GET",path="/b"} 1
+requests_total{method="POST",path="/\"quoted\""} 1
+`
+
+	assert.Equal(t, want, b.String())
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	reg := NewRegistry()
+	reg.Counter("jobs_total", "Jobs.").With().Inc()
+
+	rec := httptest.NewRecorder()
+	reg.Handler()(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, TextContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "jobs_total 1\n")
+}
+
+func TestRegistry_panics(t *testing.T) {
+	tests := map[string]func(r *Registry){
+		"invalid name": func(r *Registry) {
+			r.Counter("invalid-name", "")
+		},
+		"invalid label": func(r *Registry) {
+			r.Counter("valid", "", "invalid-label")
+		},
+		"reserved label": func(r *Registry) {
+			r.Histogram("valid", "", DefBuckets, "le")
+		},
+		"unsorted buckets": func(r *Registry) {
+			r.Histogram("valid", "", []float64{1, 0.1})
+		},
+		"duplicate": func(r *Registry) {
+			r.Counter("valid", "")
+			r.Gauge("valid", "")
+		},
+		"label count mismatch": func(r *Registry) {
+			r.Counter("valid", "", "a", "b").With("a")
+		},
+		"counter decrease": func(r *Registry) {
+			r.Counter("valid", "").With().Add(-1)
+		},
+	}
+
+	for name, test := range tests {
+		fn := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Panics(t, func() { fn(NewRegistry()) })
+		})
+	}
+}
+
+func TestHistogram_concurrent(t *testing.T) {
+	h := NewRegistry().Histogram("h", "", []float64{1}).With()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				h.Observe(0.5)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, uint64(1000), h.Count())
+	assert.Equal(t, 500.0, h.Sum())
+	assert.Equal(t, []uint64{1000}, h.cumulative())
+}
@@ -18,8 +18,10 @@ type Listener interface {
 // Server is a wrapper for server listener. It handles process termination and
 // shutdown.
 type Server struct {
-	srv Listener
-	log logger.Logger
+	srv   Listener
+	log   logger.Logger
+	hooks []func()
+	drain time.Duration
 }
 
 // New returns a new instance of Server.
@@ -27,8 +29,21 @@ func New(srv Listener, log logger.Logger) Server {
 	return Server{srv: srv, log: log}
 }
 
+// WithShutdownHook adds the funcs called as soon as the shutdown begins,
+// such as failing the readiness check.
+func (s *Server) WithShutdownHook(hooks ...func()) {
+	s.hooks = append(s.hooks, hooks...)
+}
+
+// WithDrainDelay sets the wait between calling the shutdown hooks and closing
+// the listener on a signal, so that load balancers stop sending new requests.
+// The wait ends early when the context is done.
+func (s *Server) WithDrainDelay(d time.Duration) {
+	s.drain = d
+}
+
 // Serve starts a new server listener and handles interrupt and termination
-// signals.
+// signals. The shutdown hooks are called before the listener is shut down.
 func (s Server) Serve(ctx context.Context) error {
 	errsCh := make(chan error)
 
@@ -37,9 +52,21 @@ func (s Server) Serve(ctx context.Context) error {
 		errsCh <- s.srv.ListenAndServe()
 	}()
 
-	var err error
+	var (
+		err      error
+		signaled bool
+	)
 
 	defer func() {
+		for _, hook := range s.hooks {
+			hook()
+		}
+
+		if signaled && s.drain > 0 {
+			s.log.Infof("server: draining for %s...", s.drain)
+			s.wait(ctx)
+		}
+
 		// Wait for completion before exiting.
 		timedCtx, cancel := context.WithTimeout(ctx, time.Second)
 		defer cancel()
@@ -56,6 +83,18 @@ func (s Server) Serve(ctx context.Context) error {
 	case err = <-errsCh:
 		return err
 	case <-signalCtx.Done():
+		signaled = true
 		return err
 	}
 }
+
+// wait blocks for the drain delay or until the context is done.
+func (s Server) wait(ctx context.Context) {
+	timer := time.NewTimer(s.drain)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -11,6 +11,8 @@ var (
 	ErrMissingID = errors.New("missing record ID")
 	// ErrNotFound is an error when record is not found.
 	ErrNotFound = errors.New("record is not found")
+	// ErrAlreadyExists is an error when record with the same ID exists.
+	ErrAlreadyExists = errors.New("record already exists")
 )
 
 // ID is a type that represents record's unique identifier.
@@ -47,6 +49,20 @@ func (s *InMemory[T]) Get(id ID) (t T, err error) {
 	return r.(T), nil
 }
 
+// Insert adds a new record, or returns ErrAlreadyExists if the record with
+// the same ID exists.
+func (s *InMemory[T]) Insert(r T) error {
+	if r.ID() == "" {
+		return ErrMissingID
+	}
+
+	if _, loaded := s.records.LoadOrStore(r.ID(), r); loaded {
+		return ErrAlreadyExists
+	}
+
+	return nil
+}
+
 // Update updates an existing record to inserts it if not exist..
 func (s *InMemory[T]) Update(r T) error {
 	if r.ID() == "" {
@@ -70,3 +86,10 @@ func (s *InMemory[T]) Remove(id ID) error {
 
 	return nil
 }
+
+// Range calls fn for each record until fn returns false.
+func (s *InMemory[T]) Range(fn func(T) bool) {
+	s.records.Range(func(_, r interface{}) bool {
+		return fn(r.(T))
+	})
+}
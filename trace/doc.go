@@ -0,0 +1,39 @@
+// Package trace provides distributed tracing with the W3C Trace Context
+// propagation, without depending on the OpenTelemetry libraries.
+//
+// Tracer starts spans as children of the span carried by the context, or of
+// the remote span context extracted from a carrier, such as HTTP headers or
+// message metadata. Sampled spans are exported in batches, for example to an
+// OpenTelemetry collector with the OTLPExporter.
+//
+// Basic Usage
+//
+//	tracer := trace.NewTracer(trace.Config{
+//		Exporter: trace.NewOTLPExporter(trace.OTLPConfig{
+//			Endpoint:    "http://localhost:4318/v1/traces",
+//			ServiceName: "users",
+//		}),
+//	})
+//
+//	defer tracer.Close(context.Background())
+//
+//	ctx, span := tracer.Start(ctx, "process", trace.WithAttribute("queue", "emails"))
+//	defer span.End()
+//
+//	if err := process(ctx); err != nil {
+//		span.SetError(err)
+//	}
+//
+// Message Propagation
+//
+// The trace context is passed to consumers in the message metadata.
+//
+//	m := message.Message{Data: data}
+//	trace.InjectMessage(ctx, &m)
+//
+//	ctx, span := tracer.Start(trace.ExtractMessage(ctx, m), "consume",
+//		trace.WithKind(trace.KindConsumer))
+//
+// Spans started for traces entering the process add the "trace_id" field to
+// the logger, so that logger.Logger.WithContext includes it in every log line.
+package trace
@@ -0,0 +1,204 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+const scopeName = "github.com/diptanw/go-toolkit/trace"
+
+// OTLPConfig is a configuration for the OTLP exporter.
+type OTLPConfig struct {
+	// Endpoint is the URL of the collector traces endpoint, such as
+	// http://localhost:4318/v1/traces.
+	Endpoint string
+	// ServiceName is the "service.name" resource attribute.
+	ServiceName string
+	// Headers are sent with every request, such as the authorization.
+	Headers map[string]string
+	// Client is the HTTP client sending the requests. Zero value defaults to
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// OTLPExporter is the Exporter sending spans to the OpenTelemetry collector
+// with the OTLP/JSON over HTTP protocol.
+type OTLPExporter struct {
+	cfg OTLPConfig
+}
+
+// NewOTLPExporter returns a new instance of OTLPExporter.
+func NewOTLPExporter(cfg OTLPConfig) *OTLPExporter {
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	return &OTLPExporter{cfg: cfg}
+}
+
+// Export sends the spans in a single request.
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return fmt.Errorf("marshal spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("export spans: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	// Read the response body to reuse keep-alive connection.
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096)) // nolint
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("export spans: unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              Kind           `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.Context.TraceID.String(),
+			SpanID:            s.Context.SpanID.String(),
+			TraceState:        s.Context.State,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        attributes(s.Attributes),
+			Status:            otlpStatus{Code: s.Status, Message: s.StatusMessage},
+		}
+
+		if s.Parent.IsValid() {
+			span.ParentSpanID = s.Parent.String()
+		}
+
+		out = append(out, span)
+	}
+
+	var resource []otlpKeyValue
+	if e.cfg.ServiceName != "" {
+		resource = attributes(map[string]interface{}{"service.name": e.cfg.ServiceName})
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: resource},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: scopeName},
+				Spans: out,
+			}},
+		}},
+	}
+}
+
+// attributes returns the key-value pairs sorted by key. Unsupported values
+// are formatted as strings.
+func attributes(m map[string]interface{}) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(m))
+
+	for k, v := range m {
+		var val otlpValue
+
+		switch v := v.(type) {
+		case string:
+			val.StringValue = &v
+		case bool:
+			val.BoolValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			val.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			val.IntValue = &s
+		case float64:
+			val.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			val.StringValue = &s
+		}
+
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: val})
+	}
+
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+
+	return kvs
+}
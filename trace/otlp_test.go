@@ -0,0 +1,102 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPExporter_Export(t *testing.T) {
+	var (
+		got    map[string]interface{}
+		header http.Header
+	)
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	t.Cleanup(collector.Close)
+
+	exp := NewOTLPExporter(OTLPConfig{
+		Endpoint:    collector.URL + "/v1/traces",
+		ServiceName: "users",
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+	})
+
+	start := time.Unix(1, 500)
+
+	err := exp.Export(context.Background(), []SpanData{{
+		Name: "GET /users/:id",
+		Context: SpanContext{
+			TraceID: TraceID{0x4b, 0xf9},
+			SpanID:  SpanID{0x01},
+			State:   "vendor=1",
+		},
+		Parent:        SpanID{0x02},
+		Kind:          KindServer,
+		Start:         start,
+		End:           start.Add(time.Second),
+		Status:        StatusError,
+		StatusMessage: "Internal Server Error",
+		Attributes: map[string]interface{}{
+			"http.status_code": 500,
+			"http.method":      "GET",
+			"cached":           false,
+			"ratio":            0.5,
+		},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", header.Get("Authorization"))
+
+	var want map[string]interface{}
+
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"resourceSpans": [{
+			"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "users"}}]},
+			"scopeSpans": [{
+				"scope": {"name": "github.com/diptanw/go-toolkit/trace"},
+				"spans": [{
+					"traceId": "4bf90000000000000000000000000000",
+					"spanId": "0100000000000000",
+					"parentSpanId": "0200000000000000",
+					"traceState": "vendor=1",
+					"name": "GET /users/:id",
+					"kind": 2,
+					"startTimeUnixNano": "1000000500",
+					"endTimeUnixNano": "2000000500",
+					"attributes": [
+						{"key": "cached", "value": {"boolValue": false}},
+						{"key": "http.method", "value": {"stringValue": "GET"}},
+						{"key": "http.status_code", "value": {"intValue": "500"}},
+						{"key": "ratio", "value": {"doubleValue": 0.5}}
+					],
+					"status": {"code": 2, "message": "Internal Server Error"}
+				}]
+			}]
+		}]
+	}`), &want))
+
+	assert.Equal(t, want, got)
+}
+
+func TestOTLPExporter_Export_Status(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(collector.Close)
+
+	exp := NewOTLPExporter(OTLPConfig{Endpoint: collector.URL})
+
+	err := exp.Export(context.Background(), []SpanData{{Name: "span"}})
+	assert.EqualError(t, err, "export spans: unexpected status 503")
+}
@@ -0,0 +1,166 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/diptanw/go-toolkit/message"
+)
+
+// W3C Trace Context keys.
+const (
+	TraceParentKey = "traceparent"
+	TraceStateKey  = "tracestate"
+)
+
+const (
+	traceParentLen = 55
+	maxStateLen    = 512
+)
+
+// Carrier is the storage of the propagated span context, such as HTTP
+// headers or message metadata.
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// HeaderCarrier is the Carrier of HTTP headers.
+type HeaderCarrier http.Header
+
+// Get returns the values of the header joined with commas, as tracestate
+// may be split into multiple headers.
+func (c HeaderCarrier) Get(key string) string {
+	return strings.Join(http.Header(c).Values(key), ",")
+}
+
+// Set sets the header value.
+func (c HeaderCarrier) Set(key, value string) {
+	http.Header(c).Set(key, value)
+}
+
+// MapCarrier is the Carrier of string metadata.
+type MapCarrier map[string]string
+
+// Get returns the value for the key.
+func (c MapCarrier) Get(key string) string {
+	return c[key]
+}
+
+// Set sets the value for the key.
+func (c MapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Inject writes the span context carried by ctx to the carrier. Nothing is
+// written if there is none.
+func Inject(ctx context.Context, c Carrier) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	c.Set(TraceParentKey, FormatTraceParent(sc))
+
+	if sc.State != "" {
+		c.Set(TraceStateKey, sc.State)
+	}
+}
+
+// Extract returns a copy of ctx carrying the remote span context read from
+// the carrier. The context is returned unchanged if the carrier has no valid
+// traceparent.
+func Extract(ctx context.Context, c Carrier) context.Context {
+	sc, err := ParseTraceParent(c.Get(TraceParentKey))
+	if err != nil {
+		return ctx
+	}
+
+	if state := strings.TrimSpace(c.Get(TraceStateKey)); len(state) <= maxStateLen {
+		sc.State = state
+	}
+
+	return ContextWithRemote(ctx, sc)
+}
+
+// InjectMessage writes the span context carried by ctx to the message
+// metadata.
+func InjectMessage(ctx context.Context, m *message.Message) {
+	if m.Metadata == nil {
+		m.Metadata = make(map[string]string)
+	}
+
+	Inject(ctx, MapCarrier(m.Metadata))
+}
+
+// ExtractMessage returns a copy of ctx carrying the remote span context read
+// from the message metadata.
+func ExtractMessage(ctx context.Context, m message.Message) context.Context {
+	return Extract(ctx, MapCarrier(m.Metadata))
+}
+
+// FormatTraceParent returns the traceparent value of the span context.
+func FormatTraceParent(sc SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// ParseTraceParent parses the traceparent value. Versions newer than 00 are
+// parsed by their 00 prefix, as required by the specification.
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+
+	s = strings.TrimSpace(s)
+	if len(s) < traceParentLen {
+		return sc, fmt.Errorf("invalid traceparent length %d", len(s))
+	}
+
+	version, err := decodeHex(s[:2], 1)
+	if err != nil || version[0] == 0xff {
+		return sc, fmt.Errorf("invalid traceparent version %q", s[:2])
+	}
+
+	if version[0] == 0 && len(s) != traceParentLen || len(s) > traceParentLen && s[traceParentLen] != '-' {
+		return sc, fmt.Errorf("invalid traceparent length %d", len(s))
+	}
+
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, fmt.Errorf("invalid traceparent format %q", s)
+	}
+
+	traceID, err := decodeHex(s[3:35], len(sc.TraceID))
+	if err != nil {
+		return sc, fmt.Errorf("invalid trace id: %w", err)
+	}
+
+	spanID, err := decodeHex(s[36:52], len(sc.SpanID))
+	if err != nil {
+		return sc, fmt.Errorf("invalid parent id: %w", err)
+	}
+
+	flags, err := decodeHex(s[53:55], 1)
+	if err != nil {
+		return sc, fmt.Errorf("invalid trace flags: %w", err)
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent ids %q", s)
+	}
+
+	return sc, nil
+}
+
+// decodeHex decodes the lowercase hex string of n bytes.
+func decodeHex(s string, n int) ([]byte, error) {
+	if len(s) != 2*n || strings.ToLower(s) != s {
+		return nil, fmt.Errorf("invalid hex %q", s)
+	}
+
+	return hex.DecodeString(s)
+}
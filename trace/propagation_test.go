@@ -0,0 +1,125 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diptanw/go-toolkit/message"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := map[string]struct {
+		give      string
+		wantFlags byte
+		wantErr   bool
+	}{
+		"sampled": {
+			give:      "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantFlags: FlagSampled,
+		},
+		"not sampled": {
+			give: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		},
+		"future version with extra fields": {
+			give:      "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			wantFlags: FlagSampled,
+		},
+		"invalid version": {
+			give:    "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantErr: true,
+		},
+		"version 00 with extra fields": {
+			give:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			wantErr: true,
+		},
+		"uppercase": {
+			give:    "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			wantErr: true,
+		},
+		"zero trace id": {
+			give:    "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			wantErr: true,
+		},
+		"zero parent id": {
+			give:    "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			wantErr: true,
+		},
+		"bad separator": {
+			give:    "00-4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7-01",
+			wantErr: true,
+		},
+		"short": {
+			give:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			wantErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		tc := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sc, err := ParseTraceParent(tc.give)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+			assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+			assert.Equal(t, tc.wantFlags, sc.Flags)
+		})
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	tracer := NewTracer(Config{})
+	ctx, span := tracer.Start(context.Background(), "root")
+
+	remote := Extract(context.Background(), HeaderCarrier(http.Header{
+		"Traceparent": {FormatTraceParent(span.Context())},
+		"Tracestate":  {"vendor=1", "other=2"},
+	}))
+
+	sc := SpanContextFromContext(remote)
+	assert.True(t, sc.Remote)
+	assert.Equal(t, span.Context().TraceID, sc.TraceID)
+	assert.Equal(t, span.Context().SpanID, sc.SpanID)
+	assert.Equal(t, "vendor=1,other=2", sc.State)
+
+	out := http.Header{}
+	Inject(ctx, HeaderCarrier(out))
+	assert.Equal(t, FormatTraceParent(span.Context()), out.Get("traceparent"))
+	assert.Empty(t, out.Get("tracestate"))
+
+	_, child := tracer.Start(remote, "child")
+	Inject(ContextWithSpan(ctx, child), HeaderCarrier(out))
+	assert.Equal(t, "vendor=1,other=2", out.Get("tracestate"), "tracestate is propagated unchanged")
+
+	empty := http.Header{}
+	Inject(context.Background(), HeaderCarrier(empty))
+	assert.Empty(t, empty)
+
+	assert.Equal(t, context.Background(), Extract(context.Background(), HeaderCarrier(empty)))
+}
+
+func TestInjectExtractMessage(t *testing.T) {
+	tracer := NewTracer(Config{})
+	ctx, span := tracer.Start(context.Background(), "publish", WithKind(KindProducer))
+
+	m := message.Message{Data: []byte("data")}
+	InjectMessage(ctx, &m)
+
+	assert.Equal(t, FormatTraceParent(span.Context()), m.Metadata[TraceParentKey])
+
+	_, consumer := tracer.Start(ExtractMessage(context.Background(), m), "consume", WithKind(KindConsumer))
+
+	assert.Equal(t, span.Context().TraceID, consumer.Context().TraceID)
+	assert.Equal(t, span.Context().SpanID, consumer.data.Parent)
+}
@@ -0,0 +1,221 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/diptanw/go-toolkit/internal/random"
+)
+
+// TraceID is the identifier of the trace, shared by all its spans.
+type TraceID [16]byte
+
+// String returns the lowercase hex encoding of the identifier.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the identifier has a non-zero byte.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID is the identifier of the span within the trace.
+type SpanID [8]byte
+
+// String returns the lowercase hex encoding of the identifier.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the identifier has a non-zero byte.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// FlagSampled is the trace flag set when the trace is recorded.
+const FlagSampled byte = 0x01
+
+// SpanContext is the part of the span propagated across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Flags are the trace flags, such as FlagSampled.
+	Flags byte
+	// State is the vendor specific trace state, propagated unchanged.
+	State string
+	// Remote is true when the span context is extracted from a carrier.
+	Remote bool
+}
+
+// IsValid reports whether both trace and span identifiers are valid.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled reports whether the trace is recorded.
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&FlagSampled != 0
+}
+
+// Kind is the role of the span in the trace.
+type Kind int
+
+// Available span kinds.
+const (
+	KindInternal Kind = iota + 1
+	KindServer
+	KindClient
+	KindProducer
+	KindConsumer
+)
+
+// StatusCode is the span completion status.
+type StatusCode int
+
+// Available span status codes.
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// SpanData is the snapshot of the ended span passed to the Exporter.
+type SpanData struct {
+	Name          string
+	Context       SpanContext
+	Parent        SpanID
+	Kind          Kind
+	Attributes    map[string]interface{}
+	Start         time.Time
+	End           time.Time
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Span is a timed operation within the trace. It is safe for concurrent use.
+type Span struct {
+	data   SpanData
+	tracer *Tracer
+	ended  bool
+	mu     sync.Mutex
+}
+
+// Context returns the span context.
+func (s *Span) Context() SpanContext {
+	return s.data.Context
+}
+
+// SetAttribute sets the key-value pair describing the operation. Values are
+// expected to be strings, booleans, integers or floats.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{})
+	}
+
+	s.data.Attributes[key] = value
+}
+
+// SetStatus sets the span completion status with the optional description.
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	s.data.Status = code
+	s.data.StatusMessage = msg
+}
+
+// SetError marks the span as failed with the given error. Nil error is
+// ignored.
+func (s *Span) SetError(err error) {
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+	}
+}
+
+// End completes the span and queues it for export if it is sampled. Calls
+// after the first one are ignored.
+func (s *Span) End() {
+	s.mu.Lock()
+
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+
+	s.ended = true
+	s.data.End = s.tracer.now()
+	data := s.data
+
+	s.mu.Unlock()
+
+	if data.Context.IsSampled() {
+		s.tracer.enqueue(data)
+	}
+}
+
+type contextKey int
+
+const (
+	spanKey contextKey = iota
+	remoteKey
+)
+
+// ContextWithSpan returns a copy of ctx carrying the span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey, s)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey).(*Span)
+	return s
+}
+
+// ContextWithRemote returns a copy of ctx carrying the span context extracted
+// from a remote caller, which becomes the parent of the next started span.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey, sc)
+}
+
+// SpanContextFromContext returns the context of the current span carried by
+// ctx, or the remote one if no span is started yet.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.Context()
+	}
+
+	sc, _ := ctx.Value(remoteKey).(SpanContext)
+
+	return sc
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+
+	random.Read(id[:])
+
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+
+	random.Read(id[:])
+
+	return id
+}
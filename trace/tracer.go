@@ -0,0 +1,232 @@
+package trace
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+const (
+	defBatchSize     = 512
+	defFlushInterval = 5 * time.Second
+)
+
+// Exporter sends the ended spans to a tracing backend.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// Config is a configuration for the Tracer.
+type Config struct {
+	// Exporter receives the sampled spans in batches. Zero value only
+	// propagates the trace context.
+	Exporter Exporter
+	// SampleRate is a fraction of new traces to be recorded, from 0 to 1.
+	// Zero value records all traces. Spans with a parent follow its decision.
+	SampleRate float64
+	// BatchSize is the maximum number of spans exported at once, and four
+	// times of it are queued before the new spans are dropped. Zero value
+	// defaults to 512.
+	BatchSize int
+	// FlushInterval is the period of exporting the queued spans. Zero value
+	// defaults to 5s.
+	FlushInterval time.Duration
+	// OnError is called with the errors of the background export.
+	OnError func(error)
+}
+
+// Tracer starts spans and exports them in the background.
+type Tracer struct {
+	cfg      Config
+	queue    []SpanData
+	flushCh  chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	mu       sync.Mutex
+	exportMu sync.Mutex
+	close    sync.Once
+	now      func() time.Time
+}
+
+// NewTracer returns a new instance of Tracer, which must be closed to export
+// the remaining spans.
+func NewTracer(cfg Config) *Tracer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defBatchSize
+	}
+
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defFlushInterval
+	}
+
+	t := &Tracer{
+		cfg:     cfg,
+		flushCh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		now:     time.Now,
+	}
+
+	if cfg.Exporter == nil {
+		close(t.stopped)
+	} else {
+		go t.run()
+	}
+
+	return t
+}
+
+// StartOption configures the started span.
+type StartOption func(*SpanData)
+
+// WithKind sets the span kind. Spans are internal by default.
+func WithKind(k Kind) StartOption {
+	return func(d *SpanData) {
+		d.Kind = k
+	}
+}
+
+// WithAttribute sets the span attribute.
+func WithAttribute(key string, value interface{}) StartOption {
+	return func(d *SpanData) {
+		if d.Attributes == nil {
+			d.Attributes = make(map[string]interface{})
+		}
+
+		d.Attributes[key] = value
+	}
+}
+
+// Start starts a new span as a child of the span or remote span context
+// carried by ctx, or a new trace if there is none. Returned context carries
+// the span, and adds the "trace_id" field to the logger when a trace enters
+// the process.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	s := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:  name,
+			Kind:  KindInternal,
+			Start: t.now(),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&s.data)
+	}
+
+	sc := SpanContext{SpanID: newSpanID()}
+
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Flags = parent.Flags
+		sc.State = parent.State
+		s.data.Parent = parent.SpanID
+	} else {
+		sc.TraceID = newTraceID()
+
+		if t.cfg.SampleRate <= 0 || rand.Float64() < t.cfg.SampleRate { // nolint:gosec
+			sc.Flags = FlagSampled
+		}
+	}
+
+	s.data.Context = sc
+
+	if !parent.IsValid() || parent.Remote {
+		ctx = logger.ContextWith(ctx, "trace_id", sc.TraceID.String())
+	}
+
+	return ContextWithSpan(ctx, s), s
+}
+
+// Flush exports all queued spans.
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.exportMu.Lock()
+	defer t.exportMu.Unlock()
+
+	t.mu.Lock()
+	spans := t.queue
+	t.queue = nil
+	t.mu.Unlock()
+
+	if t.cfg.Exporter == nil {
+		return nil
+	}
+
+	for len(spans) > 0 {
+		n := len(spans)
+		if n > t.cfg.BatchSize {
+			n = t.cfg.BatchSize
+		}
+
+		if err := t.cfg.Exporter.Export(ctx, spans[:n]); err != nil {
+			return err
+		}
+
+		spans = spans[n:]
+	}
+
+	return nil
+}
+
+// Close stops the background export and flushes the queued spans.
+func (t *Tracer) Close(ctx context.Context) error {
+	t.close.Do(func() {
+		close(t.done)
+	})
+
+	select {
+	case <-t.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return t.Flush(ctx)
+}
+
+func (t *Tracer) enqueue(d SpanData) {
+	if t.cfg.Exporter == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.queue) >= 4*t.cfg.BatchSize {
+		return
+	}
+
+	t.queue = append(t.queue, d)
+
+	if len(t.queue) >= t.cfg.BatchSize {
+		select {
+		case t.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+		case <-t.flushCh:
+		}
+
+		if err := t.Flush(context.Background()); err != nil && t.cfg.OnError != nil {
+			t.cfg.OnError(err)
+		}
+	}
+}
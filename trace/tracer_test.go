@@ -0,0 +1,157 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diptanw/go-toolkit/logger"
+)
+
+type fakeExporter struct {
+	batches [][]SpanData
+	err     error
+	mu      sync.Mutex
+}
+
+func (e *fakeExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.batches = append(e.batches, append([]SpanData(nil), spans...))
+
+	return e.err
+}
+
+func (e *fakeExporter) spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var spans []SpanData
+	for _, b := range e.batches {
+		spans = append(spans, b...)
+	}
+
+	return spans
+}
+
+func TestTracer_Start(t *testing.T) {
+	exp := &fakeExporter{}
+	tracer := NewTracer(Config{Exporter: exp})
+
+	var buf bytes.Buffer
+
+	log := logger.New(&buf, logger.Debug)
+
+	ctx, root := tracer.Start(context.Background(), "root", WithAttribute("key", "value"))
+	childCtx, child := tracer.Start(ctx, "child", WithKind(KindClient))
+
+	log.WithContext(childCtx).Infof("in child")
+
+	child.SetError(errors.New("failed"))
+	child.End()
+	child.SetAttribute("ignored", true)
+	root.End()
+	root.End()
+
+	require.NoError(t, tracer.Close(context.Background()))
+
+	spans := exp.spans()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, KindClient, spans[0].Kind)
+	assert.Equal(t, root.Context().TraceID, spans[0].Context.TraceID)
+	assert.Equal(t, root.Context().SpanID, spans[0].Parent)
+	assert.Equal(t, StatusError, spans[0].Status)
+	assert.Equal(t, "failed", spans[0].StatusMessage)
+	assert.NotContains(t, spans[0].Attributes, "ignored", "attributes are not changed after the end")
+
+	assert.Equal(t, "root", spans[1].Name)
+	assert.Equal(t, KindInternal, spans[1].Kind)
+	assert.False(t, spans[1].Parent.IsValid())
+	assert.Equal(t, map[string]interface{}{"key": "value"}, spans[1].Attributes)
+	assert.False(t, spans[1].End.Before(spans[1].Start))
+
+	assert.Equal(t, "INF: in child trace_id="+root.Context().TraceID.String()+"\n", buf.String(),
+		"trace id is added once per trace")
+}
+
+func TestTracer_sampling(t *testing.T) {
+	exp := &fakeExporter{}
+	tracer := NewTracer(Config{Exporter: exp, SampleRate: 1e-9})
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+
+	assert.False(t, root.Context().IsSampled())
+	assert.False(t, child.Context().IsSampled(), "child follows the parent decision")
+
+	sampled := ContextWithRemote(context.Background(), SpanContext{
+		TraceID: TraceID{1},
+		SpanID:  SpanID{1},
+		Flags:   FlagSampled,
+	})
+
+	_, remote := tracer.Start(sampled, "remote")
+	assert.True(t, remote.Context().IsSampled())
+
+	child.End()
+	root.End()
+	remote.End()
+
+	require.NoError(t, tracer.Close(context.Background()))
+	require.Len(t, exp.spans(), 1)
+	assert.Equal(t, "remote", exp.spans()[0].Name)
+}
+
+func TestTracer_batches(t *testing.T) {
+	exp := &fakeExporter{}
+	tracer := NewTracer(Config{Exporter: exp, BatchSize: 2, FlushInterval: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		_, span := tracer.Start(context.Background(), "span")
+		span.End()
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(exp.spans()) >= 2
+	}, time.Second, 10*time.Millisecond, "full batch is exported in background")
+
+	require.NoError(t, tracer.Close(context.Background()))
+
+	assert.Len(t, exp.spans(), 5)
+
+	for _, b := range exp.batches {
+		assert.LessOrEqual(t, len(b), 2)
+	}
+}
+
+func TestTracer_OnError(t *testing.T) {
+	errCh := make(chan error, 1)
+	exp := &fakeExporter{err: assert.AnError}
+
+	tracer := NewTracer(Config{
+		Exporter:      exp,
+		FlushInterval: 10 * time.Millisecond,
+		OnError:       func(err error) { errCh <- err },
+	})
+
+	_, span := tracer.Start(context.Background(), "span")
+	span.End()
+
+	select {
+	case err := <-errCh:
+		assert.Equal(t, assert.AnError, err)
+	case <-time.After(time.Second):
+		t.Fatal("export error is not reported")
+	}
+
+	require.NoError(t, tracer.Close(context.Background()))
+}